@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gantz-ai/gantz-cli/internal/mcp"
+)
+
+// terminalApprover asks for tool call approval on the gantz run terminal.
+// Prompts are shown one at a time; concurrent calls wait their turn.
+type terminalApprover struct {
+	mu    sync.Mutex
+	lines chan string
+}
+
+// newTerminalApprover returns an approver reading answers from stdin, or nil
+// if stdin is not an interactive terminal
+func newTerminalApprover() *terminalApprover {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+
+	a := &terminalApprover{lines: make(chan string)}
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			a.lines <- scanner.Text()
+		}
+		close(a.lines)
+	}()
+	return a
+}
+
+func (a *terminalApprover) Approve(ctx context.Context, req *mcp.ApprovalRequest) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// The call may have timed out while waiting for an earlier prompt
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	// Ignore anything typed while no prompt was shown
+	for drained := false; !drained; {
+		select {
+		case _, ok := <-a.lines:
+			drained = !ok
+		default:
+			drained = true
+		}
+	}
+
+	fmt.Printf("\n  %s %s %s\n", yellow("?"), yellow("Approve tool call"), cyan(req.Tool))
+	for _, line := range strings.Split(req.Action, "\n") {
+		fmt.Printf("    %s %s\n", dim("│"), line)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		fmt.Printf("  %s ", dim(fmt.Sprintf("[y/N, expires %s]", deadline.Format("15:04:05"))))
+	} else {
+		fmt.Printf("  %s ", dim("[y/N]"))
+	}
+
+	select {
+	case line, ok := <-a.lines:
+		if !ok {
+			return false, fmt.Errorf("terminal closed")
+		}
+		answer := strings.ToLower(strings.TrimSpace(line))
+		approved := answer == "y" || answer == "yes"
+		if approved {
+			fmt.Printf("  %s %s\n", green("✓"), green("Approved"))
+		} else {
+			fmt.Printf("  %s %s\n", yellow("✗"), yellow("Denied"))
+		}
+		return approved, nil
+	case <-ctx.Done():
+		fmt.Printf("\n  %s %s\n", yellow("✗"), yellow("No answer, denied"))
+		return false, ctx.Err()
+	}
+}
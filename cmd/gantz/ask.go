@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gantz-ai/gantz-cli/internal/mcp"
+)
+
+var askCmd = &cobra.Command{
+	Use:   "ask <message>",
+	Short: "Ask the connected client for input from inside a tool script",
+	Long: `Ask the MCP client that called the current tool for a value, or for an
+LLM completion with --sample. Only works inside scripts run by gantz; the
+answer is printed to stdout. Use "-" as the message to read it from stdin.
+
+Example:
+  branch=$(gantz ask "Which branch should be deployed?")
+  gantz ask "Deploy to production?" --type boolean
+  gantz ask "Pick a region" --choice eu-west-1 --choice us-east-1
+  git diff | gantz ask --sample - --system "Write a one-line commit message"`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         runAsk,
+}
+
+var (
+	askType      string
+	askChoices   []string
+	askSchema    string
+	askSample    bool
+	askSystem    string
+	askMaxTokens int
+)
+
+func runAsk(cmd *cobra.Command, args []string) error {
+	message := strings.Join(args, " ")
+	if message == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("read stdin: %w", err)
+		}
+		message = string(data)
+	}
+
+	if askSample {
+		return runSample(message)
+	}
+
+	// A single answer is asked for as an object with one "value" field
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"value": askProperty(message),
+		},
+		"required": []string{"value"},
+	}
+	if askSchema != "" {
+		if err := json.Unmarshal([]byte(askSchema), &schema); err != nil {
+			return fmt.Errorf("invalid --schema: %w", err)
+		}
+	}
+
+	data, err := mcp.HelperRequest("elicitation/create", map[string]interface{}{
+		"message":         message,
+		"requestedSchema": schema,
+	})
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Action  string                 `json:"action"`
+		Content map[string]interface{} `json:"content"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("invalid answer from client: %w", err)
+	}
+	switch result.Action {
+	case "accept":
+	case "decline":
+		return fmt.Errorf("the user declined to answer")
+	default:
+		return fmt.Errorf("the user cancelled the question")
+	}
+
+	if askSchema != "" {
+		out, _ := json.Marshal(result.Content)
+		fmt.Println(string(out))
+		return nil
+	}
+	fmt.Println(formatAnswer(result.Content["value"]))
+	return nil
+}
+
+// askProperty builds the schema of the single value asked for
+func askProperty(message string) map[string]interface{} {
+	prop := map[string]interface{}{
+		"type":        askType,
+		"description": message,
+	}
+	if len(askChoices) > 0 {
+		prop["type"] = "string"
+		prop["enum"] = askChoices
+	}
+	return prop
+}
+
+func formatAnswer(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		out, _ := json.Marshal(v)
+		return string(out)
+	}
+}
+
+func runSample(message string) error {
+	params := map[string]interface{}{
+		"messages": []map[string]interface{}{
+			{
+				"role":    "user",
+				"content": map[string]interface{}{"type": "text", "text": message},
+			},
+		},
+		"maxTokens": askMaxTokens,
+	}
+	if askSystem != "" {
+		params["systemPrompt"] = askSystem
+	}
+
+	data, err := mcp.HelperRequest("sampling/createMessage", params)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Content struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("invalid answer from client: %w", err)
+	}
+	if result.Content.Type != "text" {
+		return fmt.Errorf("client returned %s content instead of text", result.Content.Type)
+	}
+	fmt.Println(result.Content.Text)
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gantz-ai/gantz-cli/internal/audit"
+	"github.com/gantz-ai/gantz-cli/internal/config"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Query the audit log of tool calls",
+	Long: `Show tool calls recorded in the audit log set by audit.file in gantz.yaml.
+Records are shown oldest first, including rotated files.
+
+Example:
+  gantz audit
+  gantz audit --tool 'deploy_*' --since 24h
+  gantz audit --status denied --json`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runAudit,
+}
+
+var (
+	auditFile   string
+	auditTool   string
+	auditKey    string
+	auditStatus string
+	auditSince  string
+	auditLimit  int
+	auditJSON   bool
+)
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	file := auditFile
+	if file == "" {
+		cfg, err := config.Load(cfgFile)
+		if err != nil {
+			return err
+		}
+		if cfg.Audit.File == "" {
+			return fmt.Errorf("no audit log: set audit.file in %s or pass --file", cfgFile)
+		}
+		file = cfg.Audit.File
+	}
+	if len(audit.Files(file)) == 0 {
+		return fmt.Errorf("no audit log at %s", file)
+	}
+
+	since, err := parseSince(auditSince)
+	if err != nil {
+		return err
+	}
+
+	// Keep the last --limit matching records
+	var records []*audit.Record
+	err = audit.Read(file, func(rec *audit.Record) bool {
+		if !since.IsZero() && rec.Time.Before(since) {
+			return true
+		}
+		if auditTool != "" {
+			if ok, _ := path.Match(auditTool, rec.Tool); !ok {
+				return true
+			}
+		}
+		if auditKey != "" && rec.KeyID != auditKey {
+			return true
+		}
+		if auditStatus != "" && rec.Status != auditStatus {
+			return true
+		}
+		records = append(records, rec)
+		if auditLimit > 0 && len(records) > auditLimit {
+			records = records[1:]
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if auditJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, rec := range records {
+			if err := enc.Encode(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No matching tool calls")
+		return nil
+	}
+	fmt.Printf("%-19s %-24s %-12s %4s %9s %-20s %s\n", "TIME", "TOOL", "STATUS", "EXIT", "DURATION", "CLIENT", "COMMAND")
+	for _, rec := range records {
+		status := fmt.Sprintf("%-12s", rec.Status)
+		switch rec.Status {
+		case "ok":
+			status = green(status)
+		case "failed":
+			status = yellow(status)
+		default:
+			status = magenta(status)
+		}
+		exit := "-"
+		if rec.ExitCode >= 0 {
+			exit = fmt.Sprintf("%d", rec.ExitCode)
+		}
+		fmt.Printf("%-19s %-24s %s %4s %9s %-20s %s\n",
+			rec.Time.Local().Format("2006-01-02 15:04:05"),
+			rec.Tool,
+			status,
+			exit,
+			(time.Duration(rec.DurationMs) * time.Millisecond).String(),
+			auditClient(rec),
+			dim(truncate(rec.Command, 60)))
+	}
+	return nil
+}
+
+// auditClient names who made a call: its API key, token subject, client or address
+func auditClient(rec *audit.Record) string {
+	switch {
+	case rec.KeyID != "":
+		return "key " + rec.KeyID
+	case rec.Subject != "":
+		return rec.Subject
+	case rec.Client != "":
+		return rec.Client
+	case rec.ClientIP != "":
+		return rec.ClientIP
+	}
+	return "-"
+}
+
+// parseSince parses --since as a duration ago ("24h", "7d") or a date
+func parseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := parseExpiry(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: use a duration like 24h or 7d, or a date like 2006-01-02", s)
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gantz-ai/gantz-cli/internal/keys"
+)
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage API keys for clients",
+	Long: `Create, list and revoke named API keys. While any key is active,
+gantz run only accepts requests with a valid key (or the --auth token).
+Keys can be limited to some tools or tags.
+
+Example:
+  gantz keys create ci --tool run_tests --expires 90d
+  gantz keys create laptop
+  gantz keys list
+  gantz keys revoke ci`,
+}
+
+var keysCreateCmd = &cobra.Command{
+	Use:          "create <label>",
+	Short:        "Create an API key",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runKeysCreate,
+}
+
+var keysListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List API keys",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runKeysList,
+}
+
+var keysRevokeCmd = &cobra.Command{
+	Use:          "revoke <id|label>",
+	Short:        "Revoke an API key",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runKeysRevoke,
+}
+
+var (
+	keysFile    string
+	keysTools   []string
+	keysTags    []string
+	keysExpires string
+)
+
+func runKeysCreate(cmd *cobra.Command, args []string) error {
+	ttl, err := parseExpiry(keysExpires)
+	if err != nil {
+		return err
+	}
+	store, err := keys.Open(keysFile)
+	if err != nil {
+		return fmt.Errorf("open key store: %w", err)
+	}
+
+	secret, key, err := store.Create(args[0], keysTools, keysTags, ttl)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%s Created key %s (%s)\n\n", green("✓"), cyan(key.Label), dim(key.ID))
+	fmt.Printf("  %s\n\n", yellow(secret))
+	fmt.Printf("  %s %s\n", dim("Scope  "), keyScope(key))
+	fmt.Printf("  %s %s\n", dim("Expires"), keyExpiry(key))
+	fmt.Printf("\n%s\n", dim("The key is shown only once. Clients send it as: Authorization: Bearer <key>"))
+	return nil
+}
+
+func runKeysList(cmd *cobra.Command, args []string) error {
+	store, err := keys.Open(keysFile)
+	if err != nil {
+		return fmt.Errorf("open key store: %w", err)
+	}
+	list, err := store.Keys()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Printf("No API keys in %s\n", store.Path())
+		return nil
+	}
+
+	fmt.Printf("%-10s %-16s %-12s %-8s %-20s %s\n", "ID", "LABEL", "PREFIX", "STATUS", "EXPIRES", "SCOPE")
+	for _, key := range list {
+		status := key.Status()
+		switch status {
+		case "active":
+			status = green(fmt.Sprintf("%-8s", status))
+		default:
+			status = dim(fmt.Sprintf("%-8s", status))
+		}
+		fmt.Printf("%-10s %-16s %-12s %s %-20s %s\n", key.ID, key.Label, key.Prefix+"…", status, keyExpiry(key), keyScope(key))
+	}
+	return nil
+}
+
+func runKeysRevoke(cmd *cobra.Command, args []string) error {
+	store, err := keys.Open(keysFile)
+	if err != nil {
+		return fmt.Errorf("open key store: %w", err)
+	}
+	key, err := store.Revoke(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s Revoked key %s (%s)\n", green("✓"), key.Label, key.ID)
+	return nil
+}
+
+func keyScope(key *keys.Key) string {
+	if key.Unrestricted() {
+		return "all tools"
+	}
+	var parts []string
+	if len(key.Tools) > 0 {
+		parts = append(parts, "tools: "+strings.Join(key.Tools, ", "))
+	}
+	if len(key.Tags) > 0 {
+		parts = append(parts, "tags: "+strings.Join(key.Tags, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func keyExpiry(key *keys.Key) string {
+	if key.Expires == nil {
+		return "never"
+	}
+	return key.Expires.Local().Format("2006-01-02 15:04")
+}
+
+// parseExpiry parses a key lifetime such as "12h" or "90d"
+func parseExpiry(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid --expires %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid --expires %q: use a duration like 12h or 90d", s)
+	}
+	return d, nil
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
@@ -16,10 +18,16 @@ import (
 	"github.com/fatih/color"
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
 	"moul.io/banner"
 
 	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/keys"
+	"github.com/gantz-ai/gantz-cli/internal/logging"
 	"github.com/gantz-ai/gantz-cli/internal/mcp"
+	"github.com/gantz-ai/gantz-cli/internal/metrics"
+	"github.com/gantz-ai/gantz-cli/internal/trace"
 	"github.com/gantz-ai/gantz-cli/internal/tunnel"
 )
 
@@ -28,6 +36,14 @@ var (
 	cfgFile   string
 	relayURL  string
 	enableAuth bool
+	metricsAddr string
+	listenAddr  string
+	logFormat   string
+	logLevel    string
+	logFile     string
+	quiet       bool
+
+	stopTracing = func() {}
 )
 
 var (
@@ -64,6 +80,11 @@ Example:
 		fmt.Printf("  %s %-22s %s\n", dim("•"), cyan("gantz run"), dim("Start server with gantz.yaml"))
 		fmt.Printf("  %s %-22s %s\n", dim("•"), cyan("gantz init"), dim("Create sample gantz.yaml"))
 		fmt.Printf("  %s %-22s %s\n", dim("•"), cyan("gantz validate"), dim("Validate config file"))
+		fmt.Printf("  %s %-22s %s\n", dim("•"), cyan("gantz import openapi"), dim("Generate tools from OpenAPI"))
+		fmt.Printf("  %s %-22s %s\n", dim("•"), cyan("gantz ask"), dim("Ask the client from a tool script"))
+		fmt.Printf("  %s %-22s %s\n", dim("•"), cyan("gantz keys"), dim("Manage API keys"))
+		fmt.Printf("  %s %-22s %s\n", dim("•"), cyan("gantz audit"), dim("Query the audit log"))
+		fmt.Printf("  %s %-22s %s\n", dim("•"), cyan("gantz secret"), dim("Manage secrets for tools"))
 		fmt.Printf("  %s %-22s %s\n", dim("•"), cyan("gantz version"), dim("Show version info"))
 		fmt.Println()
 
@@ -109,6 +130,29 @@ var validateCmd = &cobra.Command{
 	RunE:  runValidate,
 }
 
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Generate tools from other API descriptions",
+}
+
+var importOpenAPICmd = &cobra.Command{
+	Use:   "openapi <spec.yaml>",
+	Short: "Generate HTTP tools from an OpenAPI 3 spec",
+	Long: `Read an OpenAPI 3 document and print one http tool per operation as
+gantz.yaml tool entries. Names come from operationId, parameters from the
+path, query and JSON body schemas, and descriptions from summaries.
+
+Example:
+  gantz import openapi petstore.yaml --tag pets -o tools.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportOpenAPI,
+}
+
+var (
+	importOutput string
+	importSource config.OpenAPISource
+)
+
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update gantz to the latest version",
@@ -127,12 +171,55 @@ func init() {
 	runCmd.Flags().StringVarP(&cfgFile, "config", "c", "gantz.yaml", "config file path")
 	runCmd.Flags().StringVar(&relayURL, "relay", "wss://relay.gantz.run", "relay server URL")
 	runCmd.Flags().BoolVar(&enableAuth, "auth", false, "require auth token for requests")
+	runCmd.Flags().StringVar(&keysFile, "keys-file", keys.DefaultPath(), "API key store")
+	runCmd.Flags().StringVar(&listenAddr, "listen", "", "also serve /mcp, /sse and OAuth metadata over HTTP on this address (e.g. localhost:8080)")
+	runCmd.Flags().StringVar(&metricsAddr, "metrics", "", "serve Prometheus metrics at /metrics on this address (e.g. localhost:9464)")
+	runCmd.Flags().StringVar(&logFormat, "log-format", "pretty", "log format: pretty, text or json")
+	runCmd.Flags().StringVar(&logLevel, "log-level", "info", "log level: debug, info, warn or error")
+	runCmd.Flags().StringVar(&logFile, "log-file", "", "append logs to this file instead of the terminal")
+	runCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "skip the banner and startup summary, only log")
 	validateCmd.Flags().StringVarP(&cfgFile, "config", "c", "gantz.yaml", "config file path")
+	importOpenAPICmd.Flags().StringVarP(&importOutput, "output", "o", "", "write tools to this file instead of stdout")
+	importOpenAPICmd.Flags().StringVar(&importSource.BaseURL, "base-url", "", "override the spec's server URL")
+	importOpenAPICmd.Flags().StringVar(&importSource.Prefix, "prefix", "", "prefix for generated tool names")
+	importOpenAPICmd.Flags().StringVar(&importSource.Timeout, "timeout", "", "request timeout for generated tools (e.g. 10s)")
+	importOpenAPICmd.Flags().StringSliceVar(&importSource.IncludeTags, "tag", nil, "only include operations with these tags")
+	importOpenAPICmd.Flags().StringSliceVar(&importSource.ExcludeTags, "exclude-tag", nil, "skip operations with these tags")
+	importOpenAPICmd.Flags().StringSliceVar(&importSource.IncludePaths, "path", nil, "only include paths matching these patterns")
+	importOpenAPICmd.Flags().StringSliceVar(&importSource.ExcludePaths, "exclude-path", nil, "skip paths matching these patterns")
+	importCmd.AddCommand(importOpenAPICmd)
+	askCmd.Flags().StringVar(&askType, "type", "string", "answer type: string, number, integer or boolean")
+	askCmd.Flags().StringSliceVar(&askChoices, "choice", nil, "allowed answers")
+	askCmd.Flags().StringVar(&askSchema, "schema", "", "JSON schema of an object to ask for; prints the answer as JSON")
+	askCmd.Flags().BoolVar(&askSample, "sample", false, "ask the client's LLM to complete the message instead of the user")
+	askCmd.Flags().StringVar(&askSystem, "system", "", "system prompt for --sample")
+	askCmd.Flags().IntVar(&askMaxTokens, "max-tokens", 1000, "maximum tokens for --sample")
+	keysCmd.PersistentFlags().StringVar(&keysFile, "keys-file", keys.DefaultPath(), "API key store")
+	keysCreateCmd.Flags().StringSliceVar(&keysTools, "tool", nil, "tools the key may call (glob patterns allowed; default: all)")
+	keysCreateCmd.Flags().StringSliceVar(&keysTags, "tag", nil, "tags of tools the key may call")
+	keysCreateCmd.Flags().StringVar(&keysExpires, "expires", "", "lifetime of the key, e.g. 12h or 90d (default: never)")
+	keysCmd.AddCommand(keysCreateCmd, keysListCmd, keysRevokeCmd)
+	auditCmd.Flags().StringVarP(&cfgFile, "config", "c", "gantz.yaml", "config file path")
+	auditCmd.Flags().StringVar(&auditFile, "file", "", "audit log to read (default: audit.file from the config)")
+	auditCmd.Flags().StringVar(&auditTool, "tool", "", "only show calls of tools matching this pattern")
+	auditCmd.Flags().StringVar(&auditKey, "key", "", "only show calls made with this API key ID")
+	auditCmd.Flags().StringVar(&auditStatus, "status", "", "only show calls with this status (ok, failed, denied, rate_limited, busy, not_found)")
+	auditCmd.Flags().StringVar(&auditSince, "since", "", "only show calls since a time, e.g. 24h, 7d or 2006-01-02")
+	auditCmd.Flags().IntVarP(&auditLimit, "limit", "n", 50, "show at most this many of the latest calls (0 for all)")
+	auditCmd.Flags().BoolVar(&auditJSON, "json", false, "print records as JSON Lines")
+	secretCmd.PersistentFlags().BoolVar(&secretVault, "vault", false, "use the encrypted vault instead of the keyring")
+	secretCmd.PersistentFlags().StringVar(&secretFile, "file", "", "keyring or vault file (default: in the user config directory)")
+	secretCmd.AddCommand(secretSetCmd, secretListCmd, secretRmCmd)
 
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(askCmd)
+	rootCmd.AddCommand(keysCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(secretCmd)
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(uninstallCmd)
 }
@@ -144,7 +231,15 @@ func printBanner() {
 }
 
 func runServer(cmd *cobra.Command, args []string) error {
-	printBanner()
+	closeLog, err := logging.Setup(logging.Options{Format: logFormat, Level: logLevel, File: logFile})
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	if !quiet {
+		printBanner()
+	}
 
 	// Load config
 	cfg, err := config.Load(cfgFile)
@@ -153,10 +248,22 @@ func runServer(cmd *cobra.Command, args []string) error {
 	}
 
 	// Check for updates in background
-	go checkForUpdates()
+	if !quiet {
+		go checkForUpdates()
+	}
+
+	// Export traces to an OpenTelemetry collector
+	stopTracing = trace.Setup(cfg.Tracing.Endpoint, cfg.Tracing.ServiceName, version)
+	defer func() { stopTracing() }()
 
 	// Create MCP server
 	mcpServer := mcp.NewServer(cfg)
+	defer mcpServer.Close()
+	if approver := newTerminalApprover(); approver != nil {
+		mcpServer.SetApprover(approver)
+	} else if cfg.Confirm.Policy != "" && cfg.Confirm.Policy != "none" {
+		slog.Warn("stdin is not a terminal; tool calls that need approval will be denied")
+	}
 
 	// Start config file watcher
 	go watchConfig(cfgFile, mcpServer)
@@ -165,20 +272,70 @@ func runServer(cmd *cobra.Command, args []string) error {
 	var authToken string
 	if enableAuth {
 		authToken = generateAuthToken()
+		mcpServer.SetAuthToken(authToken)
+	}
+
+	// Accept API keys from the key store
+	keyStore, err := keys.Open(keysFile)
+	if err != nil {
+		return fmt.Errorf("open key store: %w", err)
+	}
+	mcpServer.SetKeyStore(keyStore)
+
+	// Serve Prometheus metrics
+	if metricsAddr != "" {
+		listener, err := net.Listen("tcp", metricsAddr)
+		if err != nil {
+			return fmt.Errorf("serve metrics: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		go http.Serve(listener, mux)
+	}
+
+	// Serve MCP over HTTP locally, next to the relay. OAuth clients fetch the
+	// protected resource metadata from here, so auth.oauth.resource should
+	// point at this listener.
+	if listenAddr != "" {
+		listener, err := net.Listen("tcp", listenAddr)
+		if err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+		go http.Serve(listener, mcpServer.Handler())
+		slog.Info("Serving MCP over HTTP", "url", "http://"+listener.Addr().String()+"/mcp")
+	} else if cfg.Auth.OAuth != nil {
+		slog.Warn("OAuth metadata is only served with --listen; clients can't discover the authorization server")
 	}
 
 	// Connect to relay
-	fmt.Printf("  %s %s\n", dim("●"), yellow("Connecting to relay..."))
+	if !quiet {
+		fmt.Printf("  %s %s\n", dim("●"), yellow("Connecting to relay..."))
+	}
 
 	tunnelClient := tunnel.NewClient(relayURL, mcpServer, version, len(cfg.Tools), authToken)
 	tunnelClient.OnClientConnected(func(clientIP string) {
-		fmt.Printf("\n  %s %s %s\n", blue("●"), blue("Client connected"), dim(clientIP))
+		slog.Info("Client connected", "client_ip", clientIP)
 	})
 	tunnelURL, err := tunnelClient.Connect()
 	if err != nil {
 		return fmt.Errorf("connect tunnel: %w", err)
 	}
 
+	if quiet {
+		slog.Info("Connected", "url", tunnelURL, "tools", len(cfg.Tools))
+		if authToken != "" {
+			// The token is a credential: show it once on the terminal and
+			// only log enough of it to tell tokens apart
+			if term.IsTerminal(int(os.Stderr.Fd())) {
+				fmt.Fprintf(os.Stderr, "  Auth token: %s\n", authToken)
+			} else {
+				slog.Warn("Auth token not shown because stderr is not a terminal; use API keys (gantz keys create) for unattended servers")
+			}
+			slog.Info("Auth token required", "token_prefix", authToken[:8]+"…")
+		}
+		return tunnelClient.Wait()
+	}
+
 	// Clear connecting line and print success
 	fmt.Printf("\r  %s %s                    \n", green("●"), green("Connected"))
 	fmt.Println()
@@ -194,6 +351,21 @@ func runServer(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  %s\n", yellow(authToken))
 		fmt.Println()
 	}
+	if keyStore.Active() {
+		fmt.Printf("  %s %s\n\n", dim("API keys required"), dim("("+keyStore.Path()+")"))
+	}
+	if oauth := cfg.Auth.OAuth; oauth != nil {
+		fmt.Printf("  %s %s\n\n", dim("OAuth tokens from"), cyan(oauth.Issuer))
+	}
+	if cfg.Audit.File != "" {
+		fmt.Printf("  %s %s\n\n", dim("Audit log"), dim(cfg.Audit.File))
+	}
+	if metricsAddr != "" {
+		fmt.Printf("  %s %s\n\n", dim("Metrics"), cyan("http://"+metricsAddr+"/metrics"))
+	}
+	if cfg.Tracing.Endpoint != "" {
+		fmt.Printf("  %s %s\n\n", dim("Traces to"), cyan(cfg.Tracing.Endpoint))
+	}
 
 	// Print sample client link (clickable in most terminals)
 	fmt.Printf("  %s\n", dim("Sample Client"))
@@ -206,9 +378,14 @@ func runServer(cmd *cobra.Command, args []string) error {
 		toolType := dim("script")
 		if tool.IsHTTP() {
 			toolType = magenta("http")
+		} else if tool.IsGraphQL() {
+			toolType = magenta("graphql")
 		}
 		fmt.Printf("  %s %-20s %s\n", dim("•"), tool.Name, toolType)
 	}
+	for _, up := range cfg.Upstreams {
+		fmt.Printf("  %s %-20s %s\n", dim("•"), up.Prefix+"*", blue("upstream "+up.Name))
+	}
 	fmt.Println()
 
 	// Footer
@@ -226,7 +403,7 @@ func runServer(cmd *cobra.Command, args []string) error {
 func watchConfig(cfgPath string, mcpServer *mcp.Server) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		fmt.Printf("%s Failed to create file watcher: %v\n", yellow("!"), err)
+		slog.Warn("Hot-reload disabled: failed to create file watcher", "error", err)
 		return
 	}
 	defer watcher.Close()
@@ -234,14 +411,14 @@ func watchConfig(cfgPath string, mcpServer *mcp.Server) {
 	// Get absolute path for the config file
 	absPath, err := filepath.Abs(cfgPath)
 	if err != nil {
-		fmt.Printf("%s Failed to get absolute path: %v\n", yellow("!"), err)
+		slog.Warn("Hot-reload disabled: failed to get absolute path", "error", err)
 		return
 	}
 
 	// Watch the directory containing the config file (to catch editor save patterns)
 	dir := filepath.Dir(absPath)
 	if err := watcher.Add(dir); err != nil {
-		fmt.Printf("%s Failed to watch config directory: %v\n", yellow("!"), err)
+		slog.Warn("Hot-reload disabled: failed to watch config directory", "dir", dir, "error", err)
 		return
 	}
 
@@ -271,7 +448,7 @@ func watchConfig(cfgPath string, mcpServer *mcp.Server) {
 			if !ok {
 				return
 			}
-			fmt.Printf("%s File watcher error: %v\n", yellow("!"), err)
+			slog.Warn("File watcher error", "error", err)
 		}
 	}
 }
@@ -280,12 +457,32 @@ func watchConfig(cfgPath string, mcpServer *mcp.Server) {
 func reloadConfig(cfgPath string, mcpServer *mcp.Server) {
 	newCfg, err := config.Load(cfgPath)
 	if err != nil {
-		fmt.Printf("\n  %s %s %v\n", color.RedString("●"), color.RedString("Reload failed:"), err)
+		slog.Error("Reload failed", "error", err)
+		metrics.ConfigReloads.With("error").Inc()
+		mcpServer.Log("error", "gantz", map[string]interface{}{
+			"event": "config_reload_failed",
+			"error": err.Error(),
+		})
 		return
 	}
 
-	mcpServer.UpdateConfig(newCfg)
-	fmt.Printf("\n  %s %s %s tools\n", green("●"), green("Reloaded"), green(fmt.Sprintf("%d", len(newCfg.Tools))))
+	if newCfg.Tracing != mcpServer.GetConfig().Tracing {
+		stopTracing()
+		stopTracing = trace.Setup(newCfg.Tracing.Endpoint, newCfg.Tracing.ServiceName, version)
+	}
+	diff := mcpServer.UpdateConfig(newCfg)
+	metrics.ConfigReloads.With("ok").Inc()
+	attrs := []interface{}{"tools", len(newCfg.Tools)}
+	if len(diff.Added) > 0 {
+		attrs = append(attrs, "added", diff.Added)
+	}
+	if len(diff.Removed) > 0 {
+		attrs = append(attrs, "removed", diff.Removed)
+	}
+	if len(diff.Changed) > 0 {
+		attrs = append(attrs, "changed", diff.Changed)
+	}
+	slog.Info("Reloaded config", attrs...)
 }
 
 // runInit creates a sample gantz.yaml file
@@ -351,6 +548,21 @@ tools:
   #     headers:
   #       Authorization: "Bearer ${API_KEY}"
   #     extract_json: "data.temperature"
+
+  # Example 5: GraphQL query
+  # - name: get_user
+  #   description: Look up a user by ID
+  #   parameters:
+  #     - name: id
+  #       type: string
+  #       required: true
+  #   graphql:
+  #     endpoint: "https://api.example.com/graphql"
+  #     query: |
+  #       query($id: ID!) { user(id: $id) { name email } }
+  #     variables:
+  #       id: "{{id}}"
+  #     extract_json: "user"
 `
 
 	if err := os.WriteFile(filename, []byte(sampleConfig), 0644); err != nil {
@@ -381,23 +593,71 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  Name: %s\n", cyan(cfg.Name))
 	fmt.Printf("  Version: %s\n", cfg.Version)
 	fmt.Printf("  Tools: %s\n", green(fmt.Sprintf("%d", len(cfg.Tools))))
+	if len(cfg.Upstreams) > 0 {
+		fmt.Printf("  Upstreams: %s\n", green(fmt.Sprintf("%d", len(cfg.Upstreams))))
+	}
 	fmt.Println()
 
 	for i, tool := range cfg.Tools {
 		toolType := "script"
 		if tool.IsHTTP() {
 			toolType = "http"
+		} else if tool.IsGraphQL() {
+			toolType = "graphql"
 		}
 		fmt.Printf("  %d. %s %s\n", i+1, cyan(tool.Name), dim("("+toolType+")"))
 		if tool.Description != "" {
 			fmt.Printf("     %s\n", dim(tool.Description))
 		}
+		if len(tool.Tags) > 0 {
+			fmt.Printf("     %s\n", dim("tags: "+strings.Join(tool.Tags, ", ")))
+		}
+	}
+	for _, up := range cfg.Upstreams {
+		target := up.URL
+		if up.Command != "" {
+			target = strings.TrimSpace(up.Command + " " + strings.Join(up.Args, " "))
+		}
+		fmt.Printf("  %s %s %s\n", dim("↳"), cyan(up.Name), dim("(upstream: "+target+")"))
 	}
 	fmt.Println()
 
 	return nil
 }
 
+// runImportOpenAPI prints tools generated from an OpenAPI spec
+func runImportOpenAPI(cmd *cobra.Command, args []string) error {
+	importSource.Spec = args[0]
+
+	tools, err := config.ToolsFromOpenAPI(importSource)
+	if err != nil {
+		return err
+	}
+	if len(tools) == 0 {
+		return fmt.Errorf("no operations in '%s' matched the given filters", args[0])
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# Generated by 'gantz import openapi %s'\n", filepath.Base(args[0]))
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(map[string]interface{}{"tools": tools}); err != nil {
+		return fmt.Errorf("failed to encode tools: %w", err)
+	}
+	enc.Close()
+	out := buf.String()
+
+	if importOutput == "" {
+		fmt.Print(out)
+		return nil
+	}
+	if err := os.WriteFile(importOutput, []byte(out), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", importOutput, err)
+	}
+	fmt.Printf("%s Wrote %s tools to %s\n", green("✓"), green(fmt.Sprintf("%d", len(tools))), cyan(importOutput))
+	return nil
+}
+
 // checkForUpdates checks if a newer version is available
 func checkForUpdates() {
 	client := &http.Client{Timeout: 2 * time.Second}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/gantz-ai/gantz-cli/internal/secrets"
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage secrets in the local keyring or vault",
+	Long: `Store secrets for ${secret:keyring:NAME} and ${secret:vault:NAME}
+references in gantz.yaml. The keyring is a file only you can read; the vault
+is encrypted with a password from $GANTZ_VAULT_PASSWORD or a prompt.
+
+Example:
+  gantz secret set github_token
+  echo -n "$TOKEN" | gantz secret set --vault deploy_token
+  gantz secret list
+  gantz secret rm github_token`,
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:          "set <name>",
+	Short:        "Store a secret read from stdin",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runSecretSet,
+}
+
+var secretListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List stored secret names",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runSecretList,
+}
+
+var secretRmCmd = &cobra.Command{
+	Use:          "rm <name>",
+	Short:        "Remove a secret",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runSecretRm,
+}
+
+var (
+	secretVault bool
+	secretFile  string
+)
+
+// openSecretStore opens the keyring, or the vault with --vault
+func openSecretStore(creating bool) (secrets.Store, error) {
+	if !secretVault {
+		return secrets.OpenKeyring(secretFile), nil
+	}
+	password := os.Getenv(secrets.DefaultPasswordEnv)
+	if password == "" {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("set %s to the vault password", secrets.DefaultPasswordEnv)
+		}
+		var err error
+		password, err = readHidden("Vault password: ")
+		if err != nil {
+			return nil, err
+		}
+		store := secrets.OpenVault(secretFile, password)
+		if _, err := os.Stat(store.Path()); os.IsNotExist(err) && creating {
+			again, err := readHidden("Repeat password for new vault: ")
+			if err != nil {
+				return nil, err
+			}
+			if again != password {
+				return nil, fmt.Errorf("passwords do not match")
+			}
+		}
+	}
+	if password == "" {
+		return nil, fmt.Errorf("empty vault password")
+	}
+	return secrets.OpenVault(secretFile, password), nil
+}
+
+func readHidden(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	data, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(data), err
+}
+
+func runSecretSet(cmd *cobra.Command, args []string) error {
+	store, err := openSecretStore(true)
+	if err != nil {
+		return err
+	}
+
+	var value string
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		value, err = readHidden(fmt.Sprintf("Value for %s: ", args[0]))
+	} else {
+		var data []byte
+		data, err = io.ReadAll(bufio.NewReader(os.Stdin))
+		value = strings.TrimRight(string(data), "\r\n")
+	}
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("empty value for %s", args[0])
+	}
+
+	if err := store.Set(args[0], value); err != nil {
+		return err
+	}
+	fmt.Printf("%s Stored %s in %s\n", green("✓"), cyan(args[0]), dim(store.Path()))
+	return nil
+}
+
+func runSecretList(cmd *cobra.Command, args []string) error {
+	store, err := openSecretStore(false)
+	if err != nil {
+		return err
+	}
+	names, err := store.Names()
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Printf("No secrets in %s\n", store.Path())
+		return nil
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func runSecretRm(cmd *cobra.Command, args []string) error {
+	store, err := openSecretStore(false)
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("%s Removed %s from %s\n", green("✓"), args[0], dim(store.Path()))
+	return nil
+}
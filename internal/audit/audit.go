@@ -0,0 +1,196 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Record is one tool call in the audit log
+type Record struct {
+	Time        time.Time              `json:"time"`
+	Session     string                 `json:"session,omitempty"`
+	Client      string                 `json:"client,omitempty"` // Client name from initialize
+	ClientIP    string                 `json:"client_ip,omitempty"`
+	KeyID       string                 `json:"key_id,omitempty"`  // API key the call was made with
+	Subject     string                 `json:"subject,omitempty"` // OAuth token subject
+	Tool        string                 `json:"tool"`
+	Upstream    string                 `json:"upstream,omitempty"`
+	Arguments   map[string]interface{} `json:"arguments,omitempty"`
+	Command     string                 `json:"command,omitempty"` // Expanded command, URL or request
+	Status      string                 `json:"status"`            // ok, failed, denied, rate_limited, busy or not_found
+	ExitCode    int                    `json:"exit_code"`
+	DurationMs  int64                  `json:"duration_ms"`
+	OutputBytes int                    `json:"output_bytes"`
+	Error       string                 `json:"error,omitempty"`
+}
+
+// Logger appends records to a JSON Lines file, rotating it when it grows
+// past maxSize. Rotated files are named file.1 (newest) to file.N.
+type Logger struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu     sync.Mutex
+	file   *os.File // nil after Close, or if reopening the file failed
+	size   int64
+	closed bool
+}
+
+// Open opens an audit log for appending
+func Open(path string, maxSize int64, maxFiles int) (*Logger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	l := &Logger{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Path returns the file records are written to
+func (l *Logger) Path() string {
+	return l.path
+}
+
+func (l *Logger) open() error {
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// Write appends a record and syncs it to disk
+func (l *Logger) Write(rec *Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return fmt.Errorf("audit log is closed")
+	}
+	if l.file == nil {
+		// An earlier reopen failed; try again instead of giving up for good
+		if err := l.open(); err != nil {
+			return err
+		}
+	}
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			// Keep appending to the current file rather than losing records
+			slog.Warn("Audit log rotation failed", "file", l.path, "error", err)
+			if l.file == nil {
+				if err := l.open(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// rotate shifts file.N-1 to file.N, ..., file to file.1 and starts a new
+// file. If that fails, the current file is reopened, or l.file is left nil
+// if that fails too. Callers hold l.mu.
+func (l *Logger) rotate() error {
+	l.file.Close()
+	l.file = nil
+	os.Remove(rotatedName(l.path, l.maxFiles))
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		os.Rename(rotatedName(l.path, i), rotatedName(l.path, i+1))
+	}
+	if l.maxFiles > 0 {
+		if err := os.Rename(l.path, rotatedName(l.path, 1)); err != nil {
+			l.open()
+			return err
+		}
+	} else {
+		os.Remove(l.path)
+	}
+	return l.open()
+}
+
+// Close closes the log file
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func rotatedName(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// Files returns the audit log and its rotated files, oldest first
+func Files(path string) []string {
+	var files []string
+	for i := 1; ; i++ {
+		name := rotatedName(path, i)
+		if _, err := os.Stat(name); err != nil {
+			break
+		}
+		files = append([]string{name}, files...)
+	}
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	return files
+}
+
+// Read calls fn for every record in the audit log and its rotated files,
+// oldest first, until fn returns false
+func Read(path string, fn func(*Record) bool) error {
+	for _, name := range Files(path) {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var rec Record
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+				continue // Partially written line
+			}
+			if !fn(&rec) {
+				f.Close()
+				return nil
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+	}
+	return nil
+}
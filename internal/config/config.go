@@ -2,18 +2,127 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the gantz.yaml configuration
 type Config struct {
-	Name        string       `yaml:"name"`
-	Description string       `yaml:"description"`
-	Version     string       `yaml:"version"`
-	Server      ServerConfig `yaml:"server"`
-	Tools       []Tool       `yaml:"tools"`
+	Name        string                    `yaml:"name"`
+	Description string                    `yaml:"description"`
+	Version     string                    `yaml:"version"`
+	Server      ServerConfig              `yaml:"server"`
+	Tools       []Tool                    `yaml:"tools"`
+	OpenAPI     []OpenAPISource           `yaml:"openapi"`
+	Upstreams   []Upstream                `yaml:"upstreams"`
+	Resources   []Resource                `yaml:"resources"`
+	Prompts     []Prompt                  `yaml:"prompts"`
+	Confirm     ConfirmConfig             `yaml:"confirm"`
+	Auth        AuthConfig                `yaml:"auth"`
+	Limits      LimitsConfig              `yaml:"limits"`
+	Audit       AuditConfig               `yaml:"audit"`
+	Tracing     TracingConfig             `yaml:"tracing"`
+	Secrets     map[string]SecretProvider `yaml:"secrets"` // Providers for ${secret:provider:name}
+}
+
+// AuditConfig enables the audit log of tool calls
+type AuditConfig struct {
+	File      string `yaml:"file"`      // JSON Lines file; relative to the config file
+	MaxSize   string `yaml:"max_size"`  // Rotate when the file gets larger (default: 10MB)
+	MaxFiles  int    `yaml:"max_files"` // Rotated files to keep (default: 5)
+	Arguments string `yaml:"arguments"` // "redacted" (default; secret parameters masked), "all" or "none"
+}
+
+// TracingConfig exports OpenTelemetry traces to a collector over OTLP/HTTP
+type TracingConfig struct {
+	Endpoint    string `yaml:"endpoint"`     // Collector URL, e.g. http://localhost:4318 (default: $OTEL_EXPORTER_OTLP_ENDPOINT)
+	ServiceName string `yaml:"service_name"` // Default: gantz
+}
+
+// defaultAuditSize is the audit log size that triggers rotation
+const defaultAuditSize = 10 << 20
+
+// MaxSizeBytes returns the size at which the audit log is rotated
+func (a *AuditConfig) MaxSizeBytes() int64 {
+	if n, err := ParseSize(a.MaxSize); err == nil && n > 0 {
+		return n
+	}
+	return defaultAuditSize
+}
+
+// ParseSize parses a size such as "512KB", "10MB" or "1GB"
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		bytes  int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			multiplier = unit.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
+
+// LimitsConfig bounds how much work clients can cause
+type LimitsConfig struct {
+	MaxConcurrency int        `yaml:"max_concurrency"` // Tool calls running at once (default: unlimited)
+	QueueTimeout   string     `yaml:"queue_timeout"`   // How long a call waits for a free slot (default: 30s)
+	RateLimit      *RateLimit `yaml:"rate_limit"`      // Tool calls per client
+}
+
+// RateLimit is a token bucket: each client may make Requests calls Per
+// period, with bursts of up to Burst calls
+type RateLimit struct {
+	Requests int    `yaml:"requests"`
+	Per      string `yaml:"per"`   // Default: 1m
+	Burst    int    `yaml:"burst"` // Default: requests
+}
+
+// AuthConfig controls how clients authenticate
+type AuthConfig struct {
+	OAuth *OAuthConfig `yaml:"oauth"`
+}
+
+// OAuthConfig makes gantz an OAuth 2.1 resource server that accepts JWT
+// access tokens issued by an authorization server
+type OAuthConfig struct {
+	Issuer               string               `yaml:"issuer"`
+	Audience             string               `yaml:"audience"`
+	Resource             string               `yaml:"resource"`              // URL of this server (default: audience)
+	AuthorizationServers []string             `yaml:"authorization_servers"` // Default: the issuer
+	JWKSURL              string               `yaml:"jwks_url"`
+	JWKSFile             string               `yaml:"jwks_file"` // JWKS document or PEM public key
+	RequiredScopes       []string             `yaml:"required_scopes"`
+	Scopes               map[string]ToolScope `yaml:"scopes"` // Tools each scope grants (default: every token gets all tools)
+}
+
+// ToolScope selects tools by name pattern or tag
+type ToolScope struct {
+	Tools []string `yaml:"tools"`
+	Tags  []string `yaml:"tags"`
+}
+
+// ConfirmConfig controls which tool calls wait for a human to approve them
+type ConfirmConfig struct {
+	Policy  string `yaml:"policy"`  // "none" (default), "destructive" (tools not marked read-only) or "all"
+	Timeout string `yaml:"timeout"` // How long to wait for an answer before denying (default: 2m)
 }
 
 // ServerConfig holds local server configuration
@@ -21,42 +130,167 @@ type ServerConfig struct {
 	Port int `yaml:"port"`
 }
 
-// Tool represents an MCP tool definition
-type Tool struct {
+// Upstream is another MCP server whose tools gantz proxies
+type Upstream struct {
 	Name        string            `yaml:"name"`
-	Description string            `yaml:"description"`
-	Parameters  []Parameter       `yaml:"parameters"`
-	Script      ScriptConfig      `yaml:"script"`
-	HTTP        HTTPConfig        `yaml:"http"`
+	Prefix      string            `yaml:"prefix"` // Prepended to the upstream's tool names
+	Command     string            `yaml:"command"`
+	Args        []string          `yaml:"args"`
+	WorkingDir  string            `yaml:"working_dir"`
 	Environment map[string]string `yaml:"environment"`
+	URL         string            `yaml:"url"`
+	Transport   string            `yaml:"transport"` // "http" or "sse" for url upstreams (default: inferred from the URL)
+	Headers     map[string]string `yaml:"headers"`
+	Timeout     string            `yaml:"timeout"`
 }
 
-// HTTPConfig holds HTTP request configuration
-type HTTPConfig struct {
-	Method      string            `yaml:"method"`
-	URL         string            `yaml:"url"`
+// Resource is data exposed to clients through MCP resources/read. A URI with
+// {param} placeholders makes it a resource template; the values are
+// substituted into {{param}} placeholders of the source.
+type Resource struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	URI         string            `yaml:"uri"`       // Default: file:// path for files, the URL for http
+	MimeType    string            `yaml:"mime_type"` // Default: guessed from the file extension or content
+	File        string            `yaml:"file"`      // A file on disk
+	Glob        string            `yaml:"glob"`      // One resource per matching file
+	Command     string            `yaml:"command"`   // Shell command whose output is the content
+	URL         string            `yaml:"url"`       // HTTP GET whose response body is the content
 	Headers     map[string]string `yaml:"headers"`
-	Body        string            `yaml:"body"`
 	Timeout     string            `yaml:"timeout"`
-	ExtractJSON string            `yaml:"extract_json"` // JSONPath to extract from response
+}
+
+// IsTemplate returns true if the resource URI has {param} placeholders
+func (r *Resource) IsTemplate() bool {
+	return strings.Contains(r.URI, "{")
+}
+
+// Prompt is a reusable prompt template served through MCP prompts/get
+type Prompt struct {
+	Name        string           `yaml:"name"`
+	Description string           `yaml:"description"`
+	Arguments   []PromptArgument `yaml:"arguments"`
+	Template    string           `yaml:"template"` // Shorthand for a single user message
+	Messages    []PromptMessage  `yaml:"messages"`
+}
+
+// PromptArgument is a value filled into a prompt's {{placeholders}}
+type PromptArgument struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Required    bool        `yaml:"required"`
+	Completion  *Completion `yaml:"completion"`
+}
+
+// PromptMessage is one message of a prompt, either text or an embedded resource
+type PromptMessage struct {
+	Role     string `yaml:"role"` // "user" or "assistant"
+	Text     string `yaml:"text"`
+	Resource string `yaml:"resource"` // URI of a resource from the resources section
+}
+
+// Tool represents an MCP tool definition
+type Tool struct {
+	Name         string            `yaml:"name"`
+	Title        string            `yaml:"title,omitempty"` // Human-readable name shown by clients
+	Description  string            `yaml:"description,omitempty"`
+	Annotations  *ToolAnnotations  `yaml:"annotations,omitempty"`
+	Tags         []string          `yaml:"tags,omitempty"`
+	Category     string            `yaml:"category,omitempty"`
+	Confirm      *bool             `yaml:"confirm,omitempty"`         // Ask before each call; overrides confirm.policy
+	Concurrency  int               `yaml:"max_concurrency,omitempty"` // Calls of this tool running at once (default: unlimited)
+	Redact       []string          `yaml:"redact,omitempty"`          // Regexes whose matches are masked in logs and audit records
+	RedactOutput bool              `yaml:"redact_output,omitempty"`   // Also mask secrets in the output returned to the client
+	Parameters   []Parameter       `yaml:"parameters,omitempty"`
+	Script       ScriptConfig      `yaml:"script,omitempty"`
+	HTTP         HTTPConfig        `yaml:"http,omitempty"`
+	GraphQL      GraphQLConfig     `yaml:"graphql,omitempty"`
+	Environment  map[string]string `yaml:"environment,omitempty"`
+
+	EnvExpanded bool     `yaml:"-"` // Set on copies whose ${VAR} references were expanded when secrets were resolved
+	EnvValues   []string `yaml:"-"` // Values of the ${VAR} references expanded at load, masked like secrets
+}
+
+// ToolAnnotations are MCP hints about a tool's behavior. Unset hints are left
+// out, so clients apply the MCP defaults.
+type ToolAnnotations struct {
+	ReadOnly    *bool `yaml:"read_only,omitempty"`   // Does not modify its environment
+	Destructive *bool `yaml:"destructive,omitempty"` // May delete or overwrite data (default: true)
+	Idempotent  *bool `yaml:"idempotent,omitempty"`  // Repeating a call with the same arguments has no further effect
+	OpenWorld   *bool `yaml:"open_world,omitempty"`  // Interacts with external systems (default: true)
+}
+
+// HTTPConfig holds HTTP request configuration
+type HTTPConfig struct {
+	Method      string            `yaml:"method,omitempty"`
+	URL         string            `yaml:"url,omitempty"`
+	Query       map[string]string `yaml:"query,omitempty"` // Query parameters; dropped when their {{param}} is not supplied
+	Headers     map[string]string `yaml:"headers,omitempty"`
+	Body        string            `yaml:"body,omitempty"`
+	JSONBody    []string          `yaml:"json_body,omitempty"`   // Parameters sent as a JSON object body, keeping their types
+	PathParams  []string          `yaml:"path_params,omitempty"` // Parameters escaped as one path segment in the URL
+	Timeout     string            `yaml:"timeout,omitempty"`
+	ExtractJSON string            `yaml:"extract_json,omitempty"` // JSONPath to extract from response
+}
+
+// GraphQLConfig holds GraphQL request configuration
+type GraphQLConfig struct {
+	Endpoint    string            `yaml:"endpoint,omitempty"`
+	Query       string            `yaml:"query,omitempty"`
+	Variables   map[string]string `yaml:"variables,omitempty"` // variable name -> value with {{param}} placeholders
+	Headers     map[string]string `yaml:"headers,omitempty"`
+	Timeout     string            `yaml:"timeout,omitempty"`
+	ExtractJSON string            `yaml:"extract_json,omitempty"` // Path to extract from the "data" object
 }
 
 // Parameter represents a tool parameter
 type Parameter struct {
-	Name        string `yaml:"name"`
-	Type        string `yaml:"type"`
-	Description string `yaml:"description"`
-	Required    bool   `yaml:"required"`
-	Default     string `yaml:"default"`
+	Name        string      `yaml:"name"`
+	Type        string      `yaml:"type,omitempty"`
+	Description string      `yaml:"description,omitempty"`
+	Required    bool        `yaml:"required,omitempty"`
+	Default     string      `yaml:"default,omitempty"`
+	Completion  *Completion `yaml:"completion,omitempty"`
+	Secret      bool        `yaml:"secret,omitempty"` // Value is masked in logs, audit records and redacted output
+}
+
+// Completion is a source of suggestions for an argument, served through MCP
+// completion/complete. Candidates are filtered by what the user has typed.
+type Completion struct {
+	Values  []string `yaml:"values,omitempty"`  // Fixed list of candidates
+	Command string   `yaml:"command,omitempty"` // Shell command printing one candidate per line; {{value}} is the typed text
+	Glob    string   `yaml:"glob,omitempty"`    // Matching file paths are candidates
+	Cache   string   `yaml:"cache,omitempty"`   // How long command and glob results are reused (default: 10s)
 }
 
 // ScriptConfig holds script execution configuration
 type ScriptConfig struct {
-	Command    string   `yaml:"command"`
-	Args       []string `yaml:"args"`
-	Shell      string   `yaml:"shell"`
-	WorkingDir string   `yaml:"working_dir"`
-	Timeout    string   `yaml:"timeout"`
+	Command    string           `yaml:"command,omitempty"`
+	Args       []string         `yaml:"args,omitempty"`
+	Shell      string           `yaml:"shell,omitempty"`
+	WorkingDir string           `yaml:"working_dir,omitempty"`
+	Timeout    string           `yaml:"timeout,omitempty"`
+	Container  *ContainerConfig `yaml:"container,omitempty"` // Run inside a container instead of on the host
+	SSH        *SSHConfig       `yaml:"ssh,omitempty"`       // Run on a remote host instead of locally
+}
+
+// SSHConfig runs a script on a remote host over SSH
+type SSHConfig struct {
+	Host       string `yaml:"host"`                  // host or host:port
+	User       string `yaml:"user,omitempty"`        // Default: current user
+	KeyFile    string `yaml:"key_file,omitempty"`    // Default: ssh-agent, then ~/.ssh/id_*
+	KnownHosts string `yaml:"known_hosts,omitempty"` // Default: ~/.ssh/known_hosts
+	JumpHost   string `yaml:"jump_host,omitempty"`   // [user@]host[:port]
+}
+
+// ContainerConfig runs a script inside a container via the Docker/Podman API
+type ContainerConfig struct {
+	Image   string   `yaml:"image"`
+	Mounts  []string `yaml:"mounts,omitempty"`  // "host:container[:ro]"
+	Network string   `yaml:"network,omitempty"` // Network mode, e.g. "none", "bridge", "host"
+	User    string   `yaml:"user,omitempty"`
+	Pull    string   `yaml:"pull,omitempty"`   // "missing" (default), "always" or "never"
+	Socket  string   `yaml:"socket,omitempty"` // Engine socket (default: $DOCKER_HOST or auto-detected)
 }
 
 // Load reads and parses the config file
@@ -69,13 +303,25 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("cannot read '%s': %w", path, err)
 	}
 
-	// Expand environment variables
-	data = []byte(os.ExpandEnv(string(data)))
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid YAML in '%s': %w\n\n  Check for syntax errors like incorrect indentation or missing colons", path, err)
+	}
+
+	// Expand environment variables, remembering the values each tool used so
+	// they can be kept out of approval prompts and audit records
+	toolEnv := toolEnvValues(&root)
+	expandEnvNode(&root, "")
 
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := root.Decode(&cfg); err != nil {
 		return nil, fmt.Errorf("invalid YAML in '%s': %w\n\n  Check for syntax errors like incorrect indentation or missing colons", path, err)
 	}
+	for i := range cfg.Tools {
+		if i < len(toolEnv) {
+			cfg.Tools[i].EnvValues = toolEnv[i]
+		}
+	}
 
 	// Set defaults
 	if cfg.Name == "" {
@@ -88,8 +334,151 @@ func Load(path string) (*Config, error) {
 		cfg.Server.Port = 3000
 	}
 
+	// Generate tools from OpenAPI sources
+	defined := map[string]string{}
+	for _, tool := range cfg.Tools {
+		defined[tool.Name] = "the tools list"
+	}
+	for _, src := range cfg.OpenAPI {
+		if src.Spec == "" {
+			return nil, fmt.Errorf("openapi source in '%s' is missing a spec\n\n  Add 'spec: ./path/to/openapi.yaml'", path)
+		}
+		if !filepath.IsAbs(src.Spec) {
+			src.Spec = filepath.Join(filepath.Dir(path), src.Spec)
+		}
+		tools, err := ToolsFromOpenAPI(src)
+		if err != nil {
+			return nil, err
+		}
+		for _, tool := range tools {
+			if from, ok := defined[tool.Name]; ok {
+				return nil, fmt.Errorf("openapi spec '%s' generates tool '%s', which is already defined by %s\n\n  Set a 'prefix' on the source or exclude the operation", src.Spec, tool.Name, from)
+			}
+			defined[tool.Name] = "openapi spec '" + src.Spec + "'"
+		}
+		cfg.Tools = append(cfg.Tools, tools...)
+	}
+
+	// Validate confirmation policy
+	switch cfg.Confirm.Policy {
+	case "", "none", "destructive", "all":
+	default:
+		return nil, fmt.Errorf("unknown confirm.policy '%s' in '%s'\n\n  Use 'none', 'destructive' or 'all'", cfg.Confirm.Policy, path)
+	}
+	if cfg.Confirm.Timeout != "" {
+		if _, err := time.ParseDuration(cfg.Confirm.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid confirm.timeout '%s' in '%s'\n\n  Use a duration like '2m' or '30s'", cfg.Confirm.Timeout, path)
+		}
+	}
+
+	if err := validateOAuth(cfg.Auth.OAuth, path); err != nil {
+		return nil, err
+	}
+	if err := validateLimits(&cfg.Limits, path); err != nil {
+		return nil, err
+	}
+	if err := validateAudit(&cfg.Audit, path); err != nil {
+		return nil, err
+	}
+	if err := validateTracing(&cfg.Tracing, path); err != nil {
+		return nil, err
+	}
+
+	// Validate upstreams
+	upstreamNames := map[string]bool{}
+	for i, up := range cfg.Upstreams {
+		if up.Name == "" {
+			return nil, fmt.Errorf("upstream #%d is missing a name\n\n  Every upstream needs a 'name' field", i+1)
+		}
+		if upstreamNames[up.Name] {
+			return nil, fmt.Errorf("upstream '%s' is defined more than once", up.Name)
+		}
+		upstreamNames[up.Name] = true
+		if (up.Command == "") == (up.URL == "") {
+			return nil, fmt.Errorf("upstream '%s' needs exactly one of 'command' or 'url'", up.Name)
+		}
+		if up.Transport != "" && up.Transport != "http" && up.Transport != "sse" {
+			return nil, fmt.Errorf("upstream '%s' has unknown transport '%s'\n\n  Use 'http' or 'sse'", up.Name, up.Transport)
+		}
+	}
+
+	// Validate resources
+	for i, res := range cfg.Resources {
+		if res.Name == "" {
+			return nil, fmt.Errorf("resource #%d is missing a name\n\n  Every resource needs a 'name' field", i+1)
+		}
+		sources := 0
+		for _, src := range []string{res.File, res.Glob, res.Command, res.URL} {
+			if src != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return nil, fmt.Errorf("resource '%s' needs exactly one of 'file', 'glob', 'command' or 'url'", res.Name)
+		}
+		if res.File != "" && !filepath.IsAbs(res.File) {
+			cfg.Resources[i].File = filepath.Join(filepath.Dir(path), res.File)
+		}
+		if res.Glob != "" {
+			if res.URI != "" {
+				return nil, fmt.Errorf("resource '%s' uses a glob, which sets each file's URI; remove 'uri'", res.Name)
+			}
+			if !filepath.IsAbs(res.Glob) {
+				cfg.Resources[i].Glob = filepath.Join(filepath.Dir(path), res.Glob)
+			}
+			if _, err := filepath.Match(cfg.Resources[i].Glob, ""); err != nil {
+				return nil, fmt.Errorf("resource '%s' has an invalid glob: %w", res.Name, err)
+			}
+		}
+		if res.Command != "" && res.URI == "" {
+			return nil, fmt.Errorf("resource '%s' runs a command and needs a 'uri' (e.g. 'status://%s')", res.Name, res.Name)
+		}
+		if res.URI == "" && (strings.Contains(res.File, "{{") || strings.Contains(res.URL, "{{")) {
+			return nil, fmt.Errorf("resource '%s' uses {{param}} placeholders and needs a 'uri' template (e.g. 'notes://{param}')", res.Name)
+		}
+		if res.File != "" && res.URI == "" {
+			cfg.Resources[i].URI = "file://" + filepath.ToSlash(cfg.Resources[i].File)
+		}
+		if res.URL != "" && res.URI == "" {
+			cfg.Resources[i].URI = res.URL
+		}
+	}
+
+	// Validate prompts
+	promptNames := map[string]bool{}
+	for i, prompt := range cfg.Prompts {
+		if prompt.Name == "" {
+			return nil, fmt.Errorf("prompt #%d is missing a name\n\n  Every prompt needs a 'name' field", i+1)
+		}
+		if promptNames[prompt.Name] {
+			return nil, fmt.Errorf("prompt '%s' is defined more than once", prompt.Name)
+		}
+		promptNames[prompt.Name] = true
+		if (prompt.Template == "") == (len(prompt.Messages) == 0) {
+			return nil, fmt.Errorf("prompt '%s' needs either 'template' or 'messages'", prompt.Name)
+		}
+		for j, arg := range prompt.Arguments {
+			if arg.Name == "" {
+				return nil, fmt.Errorf("prompt '%s' argument #%d is missing a name", prompt.Name, j+1)
+			}
+			if err := validateCompletion(arg.Completion, path); err != nil {
+				return nil, fmt.Errorf("prompt '%s' argument '%s': %w", prompt.Name, arg.Name, err)
+			}
+		}
+		for j, msg := range prompt.Messages {
+			if msg.Role == "" {
+				cfg.Prompts[i].Messages[j].Role = "user"
+			} else if msg.Role != "user" && msg.Role != "assistant" {
+				return nil, fmt.Errorf("prompt '%s' message #%d has unknown role '%s'\n\n  Use 'user' or 'assistant'", prompt.Name, j+1, msg.Role)
+			}
+			if (msg.Text == "") == (msg.Resource == "") {
+				return nil, fmt.Errorf("prompt '%s' message #%d needs exactly one of 'text' or 'resource'", prompt.Name, j+1)
+			}
+		}
+	}
+
 	// Validate tools
-	if len(cfg.Tools) == 0 {
+	if len(cfg.Tools) == 0 && len(cfg.Upstreams) == 0 {
 		return nil, fmt.Errorf("no tools defined in '%s'\n\n  Add at least one tool with either 'script' or 'http' configuration", path)
 	}
 
@@ -99,11 +488,26 @@ func Load(path string) (*Config, error) {
 		}
 		hasScript := tool.Script.Command != "" || tool.Script.Shell != ""
 		hasHTTP := tool.HTTP.URL != ""
-		if !hasScript && !hasHTTP {
-			return nil, fmt.Errorf("tool '%s' has no action defined\n\n  Add either:\n  - script.shell: \"your command\"\n  - script.command: \"/path/to/script\"\n  - http.url: \"https://api.example.com\"", tool.Name)
+		hasGraphQL := tool.GraphQL.Endpoint != ""
+		actions := 0
+		for _, has := range []bool{hasScript, hasHTTP, hasGraphQL} {
+			if has {
+				actions++
+			}
 		}
-		if hasScript && hasHTTP {
-			return nil, fmt.Errorf("tool '%s' has both script and http defined\n\n  Use only one: either 'script' or 'http'", tool.Name)
+		if actions == 0 {
+			return nil, fmt.Errorf("tool '%s' has no action defined\n\n  Add either:\n  - script.shell: \"your command\"\n  - script.command: \"/path/to/script\"\n  - http.url: \"https://api.example.com\"\n  - graphql.endpoint: \"https://api.example.com/graphql\"", tool.Name)
+		}
+		if actions > 1 {
+			return nil, fmt.Errorf("tool '%s' has more than one action defined\n\n  Use only one of 'script', 'http' or 'graphql'", tool.Name)
+		}
+		if tool.Concurrency < 0 {
+			return nil, fmt.Errorf("tool '%s' has a negative max_concurrency", tool.Name)
+		}
+		for _, pattern := range tool.Redact {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("tool '%s' has an invalid redact pattern '%s': %w", tool.Name, pattern, err)
+			}
 		}
 
 		// Validate HTTP config
@@ -111,6 +515,42 @@ func Load(path string) (*Config, error) {
 			if tool.HTTP.Method == "" {
 				cfg.Tools[i].HTTP.Method = "GET" // Default to GET
 			}
+			if tool.HTTP.Body != "" && len(tool.HTTP.JSONBody) > 0 {
+				return nil, fmt.Errorf("tool '%s' has both http.body and http.json_body defined\n\n  Use only one of them", tool.Name)
+			}
+		}
+
+		// Validate container config
+		if ct := tool.Script.Container; ct != nil {
+			if !hasScript {
+				return nil, fmt.Errorf("tool '%s' has script.container but no script.shell or script.command", tool.Name)
+			}
+			if ct.Image == "" {
+				return nil, fmt.Errorf("tool '%s' is missing script.container.image", tool.Name)
+			}
+			switch ct.Pull {
+			case "", "missing", "always", "never":
+			default:
+				return nil, fmt.Errorf("tool '%s' has unknown script.container.pull '%s'\n\n  Use 'missing', 'always' or 'never'", tool.Name, ct.Pull)
+			}
+		}
+
+		// Validate SSH config
+		if tool.Script.SSH != nil {
+			if !hasScript {
+				return nil, fmt.Errorf("tool '%s' has script.ssh but no script.shell or script.command", tool.Name)
+			}
+			if tool.Script.SSH.Host == "" {
+				return nil, fmt.Errorf("tool '%s' is missing script.ssh.host", tool.Name)
+			}
+			if tool.Script.Container != nil {
+				return nil, fmt.Errorf("tool '%s' has both script.ssh and script.container defined\n\n  Use only one of them", tool.Name)
+			}
+		}
+
+		// Validate GraphQL config
+		if hasGraphQL && strings.TrimSpace(tool.GraphQL.Query) == "" {
+			return nil, fmt.Errorf("tool '%s' has a graphql endpoint but no query\n\n  Add a 'graphql.query' document", tool.Name)
 		}
 
 		// Validate parameters
@@ -121,12 +561,180 @@ func Load(path string) (*Config, error) {
 			if param.Type == "" {
 				cfg.Tools[i].Parameters[j].Type = "string" // Default to string
 			}
+			if err := validateCompletion(param.Completion, path); err != nil {
+				return nil, fmt.Errorf("tool '%s' parameter '%s': %w", tool.Name, param.Name, err)
+			}
 		}
 	}
 
+	if err := validateSecrets(&cfg, path); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
+// validateCompletion checks a completion source and resolves its glob
+// relative to the config file
+func validateCompletion(c *Completion, path string) error {
+	if c == nil {
+		return nil
+	}
+	sources := 0
+	if len(c.Values) > 0 {
+		sources++
+	}
+	if c.Command != "" {
+		sources++
+	}
+	if c.Glob != "" {
+		sources++
+	}
+	if sources != 1 {
+		return fmt.Errorf("completion needs exactly one of 'values', 'command' or 'glob'")
+	}
+	if c.Glob != "" {
+		if !filepath.IsAbs(c.Glob) {
+			c.Glob = filepath.Join(filepath.Dir(path), c.Glob)
+		}
+		if _, err := filepath.Match(c.Glob, ""); err != nil {
+			return fmt.Errorf("completion has an invalid glob: %w", err)
+		}
+	}
+	if c.Cache != "" {
+		if _, err := time.ParseDuration(c.Cache); err != nil {
+			return fmt.Errorf("completion has an invalid cache duration '%s'", c.Cache)
+		}
+	}
+	return nil
+}
+
+func validateOAuth(o *OAuthConfig, path string) error {
+	if o == nil {
+		return nil
+	}
+	if o.Issuer == "" || o.Audience == "" {
+		return fmt.Errorf("auth.oauth in '%s' needs 'issuer' and 'audience'", path)
+	}
+	if (o.JWKSURL == "") == (o.JWKSFile == "") {
+		return fmt.Errorf("auth.oauth in '%s' needs exactly one of 'jwks_url' or 'jwks_file'", path)
+	}
+	if o.JWKSFile != "" && !filepath.IsAbs(o.JWKSFile) {
+		o.JWKSFile = filepath.Join(filepath.Dir(path), o.JWKSFile)
+	}
+	if o.Resource == "" {
+		o.Resource = o.Audience
+	}
+	if u, err := url.Parse(o.Resource); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("auth.oauth.resource '%s' in '%s' must be the http(s) URL of this server\n\n  Set 'resource', or use a URL as 'audience'", o.Resource, path)
+	}
+	if len(o.AuthorizationServers) == 0 {
+		o.AuthorizationServers = []string{o.Issuer}
+	}
+	return nil
+}
+
+func validateLimits(l *LimitsConfig, path string) error {
+	if l.MaxConcurrency < 0 {
+		return fmt.Errorf("limits.max_concurrency in '%s' can't be negative", path)
+	}
+	if l.QueueTimeout != "" {
+		if _, err := time.ParseDuration(l.QueueTimeout); err != nil {
+			return fmt.Errorf("invalid limits.queue_timeout '%s' in '%s'\n\n  Use a duration like '30s'", l.QueueTimeout, path)
+		}
+	}
+	if r := l.RateLimit; r != nil {
+		if r.Requests <= 0 {
+			return fmt.Errorf("limits.rate_limit in '%s' needs a positive 'requests'", path)
+		}
+		if r.Per == "" {
+			r.Per = "1m"
+		}
+		if d, err := time.ParseDuration(r.Per); err != nil || d <= 0 {
+			return fmt.Errorf("invalid limits.rate_limit.per '%s' in '%s'\n\n  Use a duration like '1m'", r.Per, path)
+		}
+		if r.Burst <= 0 {
+			r.Burst = r.Requests
+		}
+	}
+	return nil
+}
+
+func validateAudit(a *AuditConfig, path string) error {
+	if a.File == "" {
+		return nil
+	}
+	if !filepath.IsAbs(a.File) {
+		a.File = filepath.Join(filepath.Dir(path), a.File)
+	}
+	if a.MaxSize != "" {
+		if _, err := ParseSize(a.MaxSize); err != nil {
+			return fmt.Errorf("invalid audit.max_size '%s' in '%s'\n\n  Use a size like '10MB'", a.MaxSize, path)
+		}
+	}
+	if a.MaxFiles < 0 {
+		return fmt.Errorf("audit.max_files in '%s' can't be negative", path)
+	}
+	if a.MaxFiles == 0 {
+		a.MaxFiles = 5
+	}
+	switch a.Arguments {
+	case "":
+		a.Arguments = "redacted"
+	case "redacted", "all", "none":
+	default:
+		return fmt.Errorf("unknown audit.arguments '%s' in '%s'\n\n  Use 'redacted', 'all' or 'none'", a.Arguments, path)
+	}
+	return nil
+}
+
+// validateTracing fills in the endpoint from the standard OTEL_ variables
+// and turns a collector URL into its traces URL
+func validateTracing(t *TracingConfig, path string) error {
+	if t.Endpoint == "" {
+		if env := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); env != "" {
+			t.Endpoint = env // Already the traces URL
+		} else {
+			t.Endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		}
+	}
+	if t.ServiceName == "" {
+		t.ServiceName = os.Getenv("OTEL_SERVICE_NAME")
+	}
+	if t.ServiceName == "" {
+		t.ServiceName = "gantz"
+	}
+	if t.Endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(t.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid tracing.endpoint '%s' in '%s'\n\n  Use the collector's OTLP/HTTP URL, like 'http://localhost:4318'", t.Endpoint, path)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+		t.Endpoint = u.String()
+	}
+	return nil
+}
+
+// Allows reports whether a tool is selected by name pattern or tag
+func (s ToolScope) Allows(tool string, tags []string) bool {
+	for _, pattern := range s.Tools {
+		if ok, _ := path.Match(pattern, tool); ok {
+			return true
+		}
+	}
+	for _, allowed := range s.Tags {
+		for _, tag := range tags {
+			if tag == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GetTool returns a tool by name
 func (c *Config) GetTool(name string) *Tool {
 	for i := range c.Tools {
@@ -137,7 +745,202 @@ func (c *Config) GetTool(name string) *Tool {
 	return nil
 }
 
+// GetPrompt returns a prompt by name
+func (c *Config) GetPrompt(name string) *Prompt {
+	for i := range c.Prompts {
+		if c.Prompts[i].Name == name {
+			return &c.Prompts[i]
+		}
+	}
+	return nil
+}
+
+// ToolDiff lists tool names that differ between two configs
+type ToolDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty returns true if the tool sets are identical
+func (d ToolDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffTools compares the tools of two configs as clients see them in
+// tools/list, so editing only a tool's script or request is not a change
+func DiffTools(prev, next *Config) ToolDiff {
+	var diff ToolDiff
+	for i := range next.Tools {
+		tool := &next.Tools[i]
+		old := prev.GetTool(tool.Name)
+		switch {
+		case old == nil:
+			diff.Added = append(diff.Added, tool.Name)
+		case !sameListing(old, tool):
+			diff.Changed = append(diff.Changed, tool.Name)
+		}
+	}
+	for _, tool := range prev.Tools {
+		if next.GetTool(tool.Name) == nil {
+			diff.Removed = append(diff.Removed, tool.Name)
+		}
+	}
+	return diff
+}
+
+// sameListing reports whether two tools have the same tools/list entry:
+// name, title, description, parameters, annotations, tags and category
+func sameListing(a, b *Tool) bool {
+	if a.Name != b.Name || a.Title != b.Title || a.Description != b.Description || a.Category != b.Category {
+		return false
+	}
+	if !reflect.DeepEqual(a.Annotations, b.Annotations) || !reflect.DeepEqual(a.Tags, b.Tags) {
+		return false
+	}
+	if len(a.Parameters) != len(b.Parameters) {
+		return false
+	}
+	for i, p := range a.Parameters {
+		q := b.Parameters[i]
+		if p.Name != q.Name || p.Type != q.Type || p.Description != q.Description || p.Required != q.Required || p.Default != q.Default {
+			return false
+		}
+	}
+	return true
+}
+
+// NeedsConfirm reports whether calls to a tool must be approved by a human.
+// readOnly is the tool's read-only hint.
+func (c *Config) NeedsConfirm(confirm *bool, readOnly bool) bool {
+	if confirm != nil {
+		return *confirm
+	}
+	switch c.Confirm.Policy {
+	case "all":
+		return true
+	case "destructive":
+		return !readOnly
+	default:
+		return false
+	}
+}
+
+// IsReadOnly returns true if the tool is annotated as read-only
+func (t *Tool) IsReadOnly() bool {
+	return t.Annotations != nil && t.Annotations.ReadOnly != nil && *t.Annotations.ReadOnly
+}
+
 // IsHTTP returns true if the tool uses HTTP configuration
 func (t *Tool) IsHTTP() bool {
 	return t.HTTP.URL != ""
 }
+
+// IsGraphQL returns true if the tool uses GraphQL configuration
+func (t *Tool) IsGraphQL() bool {
+	return t.GraphQL.Endpoint != ""
+}
+
+// noEnvExpand lists config paths whose values are passed through verbatim.
+// GraphQL documents use $name for their own variables, and prompt text is
+// prose where a literal $ is common.
+var noEnvExpand = map[string]bool{
+	"tools.graphql.query":   true,
+	"prompts.template":      true,
+	"prompts.messages.text": true,
+}
+
+// ExpandEnv expands $VAR and ${VAR} in s, leaving ${secret:...} references
+// to be resolved when a tool is called. $$ stands for a literal $.
+func ExpandEnv(s string) string {
+	return os.Expand(s, func(name string) string {
+		if strings.HasPrefix(name, "secret:") {
+			return "${" + name + "}"
+		}
+		if name == "$" {
+			return "$"
+		}
+		return os.Getenv(name)
+	})
+}
+
+// toolEnvValues returns, for each tool of the document, the values of the
+// environment variables expandEnvNode will expand in it
+func toolEnvValues(root *yaml.Node) [][]string {
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	doc := root.Content[0]
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value != "tools" || doc.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		var values [][]string
+		for _, tool := range doc.Content[i+1].Content {
+			var used []string
+			envValues(tool, "tools", &used)
+			values = append(values, used)
+		}
+		return values
+	}
+	return nil
+}
+
+// envValues appends the values of the environment variables referenced in
+// node to used, skipping the paths listed in noEnvExpand
+func envValues(node *yaml.Node, path string, used *[]string) {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			envValues(child, path, used)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			envValues(node.Content[i+1], path+"."+node.Content[i].Value, used)
+		}
+	case yaml.ScalarNode:
+		if noEnvExpand[path] {
+			return
+		}
+		os.Expand(node.Value, func(name string) string {
+			if name == "$" || strings.HasPrefix(name, "secret:") {
+				return ""
+			}
+			if v := os.Getenv(name); v != "" {
+				*used = append(*used, v)
+			}
+			return ""
+		})
+	}
+}
+
+// expandEnvNode expands ${VAR} references in every scalar value of the
+// document, skipping the paths listed in noEnvExpand
+func expandEnvNode(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			expandEnvNode(child, path)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			expandEnvNode(node.Content[i+1], key)
+		}
+	case yaml.ScalarNode:
+		if noEnvExpand[path] {
+			return
+		}
+		expanded := ExpandEnv(node.Value)
+		if expanded != node.Value {
+			node.Value = expanded
+			// Let plain scalars be re-resolved, so "port: ${PORT}" still decodes as an int
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPISource generates HTTP tools from an OpenAPI 3 document
+type OpenAPISource struct {
+	Spec         string            `yaml:"spec"`          // Path to the OpenAPI document (YAML or JSON)
+	BaseURL      string            `yaml:"base_url"`      // Overrides the document's first server URL
+	Prefix       string            `yaml:"prefix"`        // Prepended to every generated tool name
+	Headers      map[string]string `yaml:"headers"`       // Added to every generated request (e.g. auth)
+	Timeout      string            `yaml:"timeout"`       // Request timeout for generated tools
+	IncludeTags  []string          `yaml:"include_tags"`  // Only operations with one of these tags
+	ExcludeTags  []string          `yaml:"exclude_tags"`  // Skip operations with any of these tags
+	IncludePaths []string          `yaml:"include_paths"` // Only paths matching one of these patterns
+	ExcludePaths []string          `yaml:"exclude_paths"` // Skip paths matching any of these patterns
+}
+
+type openAPIDocument struct {
+	OpenAPI string                     `yaml:"openapi"`
+	Servers []openAPIServer            `yaml:"servers"`
+	Paths   map[string]openAPIPathItem `yaml:"paths"`
+
+	Components struct {
+		Schemas       map[string]*openAPISchema      `yaml:"schemas"`
+		Parameters    map[string]*openAPIParameter   `yaml:"parameters"`
+		RequestBodies map[string]*openAPIRequestBody `yaml:"requestBodies"`
+	} `yaml:"components"`
+}
+
+type openAPIServer struct {
+	URL       string `yaml:"url"`
+	Variables map[string]struct {
+		Default string `yaml:"default"`
+	} `yaml:"variables"`
+}
+
+type openAPIPathItem struct {
+	Parameters []*openAPIParameter `yaml:"parameters"`
+	Get        *openAPIOperation   `yaml:"get"`
+	Put        *openAPIOperation   `yaml:"put"`
+	Post       *openAPIOperation   `yaml:"post"`
+	Delete     *openAPIOperation   `yaml:"delete"`
+	Patch      *openAPIOperation   `yaml:"patch"`
+	Head       *openAPIOperation   `yaml:"head"`
+	Options    *openAPIOperation   `yaml:"options"`
+}
+
+type openAPIOperation struct {
+	OperationID string              `yaml:"operationId"`
+	Summary     string              `yaml:"summary"`
+	Description string              `yaml:"description"`
+	Tags        []string            `yaml:"tags"`
+	Parameters  []*openAPIParameter `yaml:"parameters"`
+	RequestBody *openAPIRequestBody `yaml:"requestBody"`
+}
+
+type openAPIParameter struct {
+	Ref         string         `yaml:"$ref"`
+	Name        string         `yaml:"name"`
+	In          string         `yaml:"in"`
+	Description string         `yaml:"description"`
+	Required    bool           `yaml:"required"`
+	Schema      *openAPISchema `yaml:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Ref         string `yaml:"$ref"`
+	Description string `yaml:"description"`
+	Required    bool   `yaml:"required"`
+	Content     map[string]struct {
+		Schema *openAPISchema `yaml:"schema"`
+	} `yaml:"content"`
+}
+
+type openAPISchema struct {
+	Ref         string                    `yaml:"$ref"`
+	Type        openAPIType               `yaml:"type"`
+	Description string                    `yaml:"description"`
+	Properties  map[string]*openAPISchema `yaml:"properties"`
+	Required    []string                  `yaml:"required"`
+	Default     interface{}               `yaml:"default"`
+	Enum        []interface{}             `yaml:"enum"`
+	AllOf       []*openAPISchema          `yaml:"allOf"`
+}
+
+// openAPIType accepts both "type: string" (3.0) and "type: [string, null]" (3.1)
+type openAPIType string
+
+func (t *openAPIType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		for _, item := range node.Content {
+			if item.Value != "null" {
+				*t = openAPIType(item.Value)
+				return nil
+			}
+		}
+		return nil
+	}
+	*t = openAPIType(node.Value)
+	return nil
+}
+
+// loadOpenAPI reads an OpenAPI 3 document from disk
+func loadOpenAPI(specPath string) (*openAPIDocument, error) {
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read OpenAPI spec '%s': %w", specPath, err)
+	}
+
+	var doc openAPIDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI spec '%s': %w", specPath, err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI spec '%s': expected 'openapi: 3.x', got '%s'", specPath, doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+// ToolsFromOpenAPI generates one HTTP tool per operation in the source's spec
+func ToolsFromOpenAPI(src OpenAPISource) ([]Tool, error) {
+	doc, err := loadOpenAPI(src.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := src.BaseURL
+	if baseURL == "" && len(doc.Servers) > 0 {
+		baseURL = doc.Servers[0].URL
+		for name, v := range doc.Servers[0].Variables {
+			baseURL = strings.ReplaceAll(baseURL, "{"+name+"}", v.Default)
+		}
+	}
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, fmt.Errorf("OpenAPI spec '%s' has no absolute server URL\n\n  Set 'base_url' on the openapi source (e.g. https://api.example.com)", src.Spec)
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var tools []Tool
+	for _, p := range paths {
+		if !matchOpenAPIPath(p, src.IncludePaths, src.ExcludePaths) {
+			continue
+		}
+		item := doc.Paths[p]
+		for _, op := range []struct {
+			method string
+			op     *openAPIOperation
+		}{
+			{"GET", item.Get}, {"POST", item.Post}, {"PUT", item.Put}, {"PATCH", item.Patch},
+			{"DELETE", item.Delete}, {"HEAD", item.Head}, {"OPTIONS", item.Options},
+		} {
+			if op.op == nil || !matchOpenAPITags(op.op.Tags, src.IncludeTags, src.ExcludeTags) {
+				continue
+			}
+			tool, err := doc.toolFromOperation(src, baseURL, p, op.method, item.Parameters, op.op)
+			if err != nil {
+				return nil, fmt.Errorf("OpenAPI spec '%s': %s %s: %w", src.Spec, op.method, p, err)
+			}
+			tools = append(tools, tool)
+		}
+	}
+	return tools, nil
+}
+
+func (doc *openAPIDocument) toolFromOperation(src OpenAPISource, baseURL, p, method string, shared []*openAPIParameter, op *openAPIOperation) (Tool, error) {
+	name := op.OperationID
+	if name == "" {
+		name = strings.ToLower(method) + "_" + p
+	}
+
+	description := op.Summary
+	if description == "" {
+		description = op.Description
+	}
+
+	tool := Tool{
+		Name:        src.Prefix + sanitizeToolName(name),
+		Description: description,
+		Annotations: methodAnnotations(method),
+		Tags:        op.Tags,
+		HTTP: HTTPConfig{
+			Method:  method,
+			URL:     baseURL + p,
+			Timeout: src.Timeout,
+		},
+	}
+	for k, v := range src.Headers {
+		if tool.HTTP.Headers == nil {
+			tool.HTTP.Headers = map[string]string{}
+		}
+		tool.HTTP.Headers[k] = v
+	}
+
+	// Operation parameters override path-level ones with the same name and location
+	params := map[string]*openAPIParameter{}
+	var order []string
+	for _, raw := range append(append([]*openAPIParameter{}, shared...), op.Parameters...) {
+		param, err := doc.resolveParameter(raw)
+		if err != nil {
+			return Tool{}, err
+		}
+		key := param.In + ":" + param.Name
+		if _, ok := params[key]; !ok {
+			order = append(order, key)
+		}
+		params[key] = param
+	}
+
+	for _, key := range order {
+		param := params[key]
+		schema := doc.resolveSchema(param.Schema)
+		switch param.In {
+		case "path":
+			tool.HTTP.URL = strings.ReplaceAll(tool.HTTP.URL, "{"+param.Name+"}", "{{"+param.Name+"}}")
+			tool.HTTP.PathParams = append(tool.HTTP.PathParams, param.Name)
+			param.Required = true
+		case "query":
+			if tool.HTTP.Query == nil {
+				tool.HTTP.Query = map[string]string{}
+			}
+			tool.HTTP.Query[param.Name] = "{{" + param.Name + "}}"
+		case "header":
+			// Optional headers would be sent with an unexpanded placeholder
+			if !param.Required {
+				continue
+			}
+			if tool.HTTP.Headers == nil {
+				tool.HTTP.Headers = map[string]string{}
+			}
+			tool.HTTP.Headers[param.Name] = "{{" + param.Name + "}}"
+		default:
+			continue
+		}
+		tool.Parameters = append(tool.Parameters, parameterFromSchema(param.Name, param.Description, param.Required, schema))
+	}
+
+	if op.RequestBody != nil {
+		body, err := doc.resolveRequestBody(op.RequestBody)
+		if err != nil {
+			return Tool{}, err
+		}
+		if err := doc.addBodyParameters(&tool, body); err != nil {
+			return Tool{}, err
+		}
+	}
+
+	return tool, nil
+}
+
+// addBodyParameters exposes the properties of a JSON object body as tool parameters
+func (doc *openAPIDocument) addBodyParameters(tool *Tool, body *openAPIRequestBody) error {
+	media, ok := body.Content["application/json"]
+	if !ok || media.Schema == nil {
+		for contentType := range body.Content {
+			return fmt.Errorf("unsupported request body content type '%s' (only application/json is supported)", contentType)
+		}
+		return nil
+	}
+
+	schema := doc.resolveSchema(media.Schema)
+	if (schema.Type != "" && schema.Type != "object") || len(schema.Properties) == 0 {
+		return fmt.Errorf("request body must be a JSON object with properties")
+	}
+
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop := doc.resolveSchema(schema.Properties[name])
+		tool.Parameters = append(tool.Parameters, parameterFromSchema(name, prop.Description, body.Required && required[name], prop))
+		tool.HTTP.JSONBody = append(tool.HTTP.JSONBody, name)
+	}
+	return nil
+}
+
+func parameterFromSchema(name, description string, required bool, schema *openAPISchema) Parameter {
+	param := Parameter{
+		Name:        name,
+		Type:        "string",
+		Description: description,
+		Required:    required,
+	}
+	if schema == nil {
+		return param
+	}
+	switch schema.Type {
+	case "integer", "number", "boolean", "array", "object":
+		param.Type = string(schema.Type)
+	}
+	if param.Description == "" {
+		param.Description = schema.Description
+	}
+	if len(schema.Enum) > 0 {
+		values := make([]string, len(schema.Enum))
+		for i, v := range schema.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		param.Description = strings.TrimSpace(param.Description + " (one of: " + strings.Join(values, ", ") + ")")
+	}
+	if schema.Default != nil {
+		param.Default = fmt.Sprint(schema.Default)
+	}
+	return param
+}
+
+func (doc *openAPIDocument) resolveParameter(param *openAPIParameter) (*openAPIParameter, error) {
+	if param.Ref == "" {
+		p := *param
+		return &p, nil
+	}
+	name := strings.TrimPrefix(param.Ref, "#/components/parameters/")
+	resolved, ok := doc.Components.Parameters[name]
+	if !ok || name == param.Ref {
+		return nil, fmt.Errorf("cannot resolve parameter reference '%s'", param.Ref)
+	}
+	p := *resolved
+	return &p, nil
+}
+
+func (doc *openAPIDocument) resolveRequestBody(body *openAPIRequestBody) (*openAPIRequestBody, error) {
+	if body.Ref == "" {
+		return body, nil
+	}
+	name := strings.TrimPrefix(body.Ref, "#/components/requestBodies/")
+	resolved, ok := doc.Components.RequestBodies[name]
+	if !ok || name == body.Ref {
+		return nil, fmt.Errorf("cannot resolve request body reference '%s'", body.Ref)
+	}
+	return resolved, nil
+}
+
+// resolveSchema follows $ref and merges allOf, returning an empty schema for
+// anything it cannot resolve
+func (doc *openAPIDocument) resolveSchema(schema *openAPISchema) *openAPISchema {
+	return doc.resolveSchemaDepth(schema, 0)
+}
+
+func (doc *openAPIDocument) resolveSchemaDepth(schema *openAPISchema, depth int) *openAPISchema {
+	if schema == nil || depth > 16 {
+		return &openAPISchema{}
+	}
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		resolved, ok := doc.Components.Schemas[name]
+		if !ok {
+			return &openAPISchema{}
+		}
+		return doc.resolveSchemaDepth(resolved, depth+1)
+	}
+	if len(schema.AllOf) == 0 {
+		return schema
+	}
+
+	merged := *schema
+	merged.Properties = map[string]*openAPISchema{}
+	for name, prop := range schema.Properties {
+		merged.Properties[name] = prop
+	}
+	for _, part := range schema.AllOf {
+		resolved := doc.resolveSchemaDepth(part, depth+1)
+		if merged.Type == "" {
+			merged.Type = resolved.Type
+		}
+		for name, prop := range resolved.Properties {
+			merged.Properties[name] = prop
+		}
+		merged.Required = append(merged.Required, resolved.Required...)
+	}
+	return &merged
+}
+
+func matchOpenAPITags(tags, include, exclude []string) bool {
+	for _, tag := range tags {
+		for _, ex := range exclude {
+			if tag == ex {
+				return false
+			}
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		for _, in := range include {
+			if tag == in {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchOpenAPIPath matches a path against include/exclude patterns. Patterns
+// use path.Match syntax, and a trailing "*" also matches deeper paths.
+func matchOpenAPIPath(p string, include, exclude []string) bool {
+	for _, pattern := range exclude {
+		if matchPathPattern(pattern, p) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		if matchPathPattern(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPathPattern(pattern, p string) bool {
+	if ok, _ := path.Match(pattern, p); ok {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && !strings.ContainsAny(prefix, "*?[") {
+		return strings.HasPrefix(p, prefix)
+	}
+	return false
+}
+
+var toolNameInvalid = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// sanitizeToolName turns an operationId or path into a valid tool name
+func sanitizeToolName(name string) string {
+	name = strings.ReplaceAll(name, "{", "")
+	name = strings.ReplaceAll(name, "}", "")
+	name = toolNameInvalid.ReplaceAllString(name, "_")
+	return strings.Trim(name, "_")
+}
+
+// methodAnnotations derives tool hints from the HTTP method's semantics
+func methodAnnotations(method string) *ToolAnnotations {
+	yes, no := true, false
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return &ToolAnnotations{ReadOnly: &yes, OpenWorld: &yes}
+	case "PUT":
+		return &ToolAnnotations{ReadOnly: &no, Idempotent: &yes, OpenWorld: &yes}
+	case "DELETE":
+		return &ToolAnnotations{ReadOnly: &no, Destructive: &yes, Idempotent: &yes, OpenWorld: &yes}
+	default:
+		return &ToolAnnotations{ReadOnly: &no, OpenWorld: &yes}
+	}
+}
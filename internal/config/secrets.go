@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// SecretProvider is a source for ${secret:provider:name} references, which
+// are resolved each time a tool is called
+type SecretProvider struct {
+	Type        string `yaml:"type,omitempty"`         // env, dotenv, vault, keyring or command (default: the provider's name)
+	File        string `yaml:"file,omitempty"`         // dotenv, vault and keyring file; relative to the config file
+	PasswordEnv string `yaml:"password_env,omitempty"` // vault: variable holding the password (default: GANTZ_VAULT_PASSWORD)
+	Command     string `yaml:"command,omitempty"`      // command: shell command printing the secret; {{name}} is its name
+	Cache       string `yaml:"cache,omitempty"`        // command: how long values are reused (default: not reused)
+}
+
+// SecretRef is a ${secret:provider:name} reference in a config value
+type SecretRef struct {
+	Provider   string
+	Name       string
+	Start, End int // Position in the value
+}
+
+var secretRefPattern = regexp.MustCompile(`\$\{secret:([A-Za-z0-9_-]+):([^}]+)\}`)
+
+// SecretRefs returns the secret references in s
+func SecretRefs(s string) []SecretRef {
+	var refs []SecretRef
+	for _, m := range secretRefPattern.FindAllStringSubmatchIndex(s, -1) {
+		refs = append(refs, SecretRef{
+			Provider: s[m[2]:m[3]],
+			Name:     s[m[4]:m[5]],
+			Start:    m[0],
+			End:      m[1],
+		})
+	}
+	return refs
+}
+
+// ToolHasSecrets reports whether a tool's script, request or environment
+// contains secret references
+func ToolHasSecrets(t *Tool) bool {
+	found := false
+	toolSecretFields(t, func(s string) {
+		if !found && secretRefPattern.MatchString(s) {
+			found = true
+		}
+	})
+	return found
+}
+
+// toolSecretFields calls fn for every tool field that may hold secret references
+func toolSecretFields(t *Tool, fn func(string)) {
+	fn(t.Script.Shell)
+	fn(t.Script.Command)
+	for _, arg := range t.Script.Args {
+		fn(arg)
+	}
+	fn(t.HTTP.URL)
+	fn(t.HTTP.Body)
+	for _, m := range []map[string]string{t.HTTP.Query, t.HTTP.Headers, t.GraphQL.Headers, t.GraphQL.Variables, t.Environment} {
+		for _, v := range m {
+			fn(v)
+		}
+	}
+	fn(t.GraphQL.Endpoint)
+}
+
+// builtinSecretProviders can be referenced without being configured
+var builtinSecretProviders = map[string]bool{"env": true, "dotenv": true, "vault": true, "keyring": true}
+
+// validateSecrets checks the secret providers and the references tools make
+// to them. Referenced built-in providers are added with their defaults.
+func validateSecrets(cfg *Config, path string) error {
+	for name, p := range cfg.Secrets {
+		if p.Type == "" {
+			p.Type = name
+		}
+		switch p.Type {
+		case "env", "keyring":
+		case "dotenv", "vault":
+		case "command":
+			if p.Command == "" {
+				return fmt.Errorf("secret provider '%s' in '%s' is missing a command\n\n  Add e.g. command: \"pass show {{name}}\"", name, path)
+			}
+			if p.Cache != "" {
+				if _, err := time.ParseDuration(p.Cache); err != nil {
+					return fmt.Errorf("invalid cache '%s' for secret provider '%s' in '%s'\n\n  Use a duration like '5m'", p.Cache, name, path)
+				}
+			}
+		default:
+			return fmt.Errorf("secret provider '%s' in '%s' has unknown type '%s'\n\n  Use 'env', 'dotenv', 'vault', 'keyring' or 'command'", name, path, p.Type)
+		}
+		if p.Type == "dotenv" && p.File == "" {
+			p.File = ".env"
+		}
+		if p.File != "" && !filepath.IsAbs(p.File) {
+			p.File = filepath.Join(filepath.Dir(path), p.File)
+		}
+		cfg.Secrets[name] = p
+	}
+
+	for i := range cfg.Tools {
+		tool := &cfg.Tools[i]
+		var err error
+		toolSecretFields(tool, func(s string) {
+			for _, ref := range SecretRefs(s) {
+				if err != nil {
+					return
+				}
+				if _, ok := cfg.Secrets[ref.Provider]; ok {
+					continue
+				}
+				if !builtinSecretProviders[ref.Provider] {
+					err = fmt.Errorf("tool '%s' uses unknown secret provider '%s'\n\n  Add it under 'secrets:' or use 'env', 'dotenv', 'vault' or 'keyring'", tool.Name, ref.Provider)
+					return
+				}
+				if cfg.Secrets == nil {
+					cfg.Secrets = make(map[string]SecretProvider)
+				}
+				p := SecretProvider{Type: ref.Provider}
+				if p.Type == "dotenv" {
+					p.File = filepath.Join(filepath.Dir(path), ".env")
+				}
+				cfg.Secrets[ref.Provider] = p
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,401 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/trace"
+)
+
+// dockerAPIVersion is understood by Docker 20.10+ and Podman's compat API
+const dockerAPIVersion = "v1.41"
+
+// containerClient talks to the Docker Engine API (or Podman's compatible API)
+type containerClient struct {
+	http *http.Client
+	base string
+}
+
+// containerClients keeps one client per engine socket, so calls reuse its
+// connections
+type containerClients struct {
+	mu       sync.Mutex
+	bySocket map[string]*containerClient
+}
+
+// get returns the client for the configured socket, $DOCKER_HOST, or the
+// first Docker/Podman socket found on this machine
+func (cc *containerClients) get(socket string) (*containerClient, error) {
+	socket, err := containerSocket(socket)
+	if err != nil {
+		return nil, err
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if c, ok := cc.bySocket[socket]; ok {
+		return c, nil
+	}
+	if cc.bySocket == nil {
+		cc.bySocket = make(map[string]*containerClient)
+	}
+	c := newContainerClient(socket)
+	cc.bySocket[socket] = c
+	return c, nil
+}
+
+// Close closes the idle connections of all clients
+func (cc *containerClients) Close() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for socket, c := range cc.bySocket {
+		c.http.CloseIdleConnections()
+		delete(cc.bySocket, socket)
+	}
+}
+
+// containerSocket resolves the engine socket to use
+func containerSocket(socket string) (string, error) {
+	if socket == "" {
+		socket = os.Getenv("DOCKER_HOST")
+	}
+	if socket == "" {
+		candidates := []string{"/var/run/docker.sock"}
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+			candidates = append(candidates, filepath.Join(dir, "podman", "podman.sock"))
+		}
+		candidates = append(candidates, "/run/podman/podman.sock")
+		for _, c := range candidates {
+			if _, err := os.Stat(c); err == nil {
+				socket = "unix://" + c
+				break
+			}
+		}
+	}
+	if socket == "" {
+		return "", fmt.Errorf("no Docker or Podman socket found (set script.container.socket or DOCKER_HOST)")
+	}
+	return socket, nil
+}
+
+// newContainerClient connects to a tcp:// or unix:// engine socket
+func newContainerClient(socket string) *containerClient {
+	if strings.HasPrefix(socket, "tcp://") {
+		return &containerClient{
+			http: &http.Client{},
+			base: "http://" + strings.TrimPrefix(socket, "tcp://") + "/" + dockerAPIVersion,
+		}
+	}
+
+	path := strings.TrimPrefix(socket, "unix://")
+	return &containerClient{
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+		base: "http://docker/" + dockerAPIVersion,
+	}
+}
+
+// do sends an API request and decodes a JSON response into out (if non-nil)
+func (c *containerClient) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	resp, err := c.stream(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// stream sends an API request and returns the response for the caller to read
+func (c *containerClient) stream(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("container engine: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		if apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+		return nil, &containerAPIError{status: resp.StatusCode, message: apiErr.Message}
+	}
+	return resp, nil
+}
+
+type containerAPIError struct {
+	status  int
+	message string
+}
+
+func (e *containerAPIError) Error() string {
+	return fmt.Sprintf("container engine: %s", e.message)
+}
+
+// ensureImage pulls the image according to the pull policy
+func (c *containerClient) ensureImage(ctx context.Context, image, policy string) error {
+	if policy == "never" {
+		return nil
+	}
+	if policy != "always" {
+		err := c.do(ctx, http.MethodGet, "/images/"+image+"/json", nil, nil)
+		if err == nil {
+			return nil
+		}
+		if apiErr, ok := err.(*containerAPIError); !ok || apiErr.status != http.StatusNotFound {
+			return err
+		}
+	}
+
+	name, tag := image, "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name, tag = image[:i], image[i+1:]
+	}
+	if i := strings.Index(image, "@"); i >= 0 {
+		name, tag = image[:i], image[i+1:]
+	}
+
+	query := url.Values{"fromImage": {name}, "tag": {tag}}
+	resp, err := c.stream(ctx, http.MethodPost, "/images/create?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("pull %s: %w", image, err)
+	}
+	defer resp.Body.Close()
+
+	// The pull progress stream reports failures inline
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var progress struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&progress); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("pull %s: %w", image, err)
+		}
+		if progress.Error != "" {
+			return fmt.Errorf("pull %s: %s", image, progress.Error)
+		}
+	}
+}
+
+// executeContainer runs a tool's script inside a fresh container
+func (e *Executor) executeContainer(ctx context.Context, tool *config.Tool, args map[string]interface{}, start time.Time) *Result {
+	ct := tool.Script.Container
+
+	fail := func(format string, err error) *Result {
+		return &Result{
+			Output:   fmt.Sprintf(format, err),
+			ExitCode: -1,
+			Duration: time.Since(start),
+			Error:    err,
+		}
+	}
+
+	client, err := e.containers.get(ct.Socket)
+	if err != nil {
+		return fail("Container engine unavailable: %v", err)
+	}
+
+	if err := client.ensureImage(ctx, ct.Image, ct.Pull); err != nil {
+		return fail("Failed to prepare image: %v", err)
+	}
+
+	var cmd []string
+	if tool.Script.Shell != "" {
+		cmd = []string{"/bin/sh", "-c", expandArgs(tool.Script.Shell, args)}
+	} else {
+		cmd = []string{tool.Script.Command}
+		for _, arg := range tool.Script.Args {
+			cmd = append(cmd, expandArgs(arg, args))
+		}
+	}
+
+	// Only tool-defined variables are passed in, so the toolchain stays reproducible
+	var env []string
+	for k, v := range tool.Environment {
+		env = append(env, fmt.Sprintf("%s=%s", k, expandEnv(tool, v)))
+	}
+	for k, v := range args {
+		env = append(env, fmt.Sprintf("GANTZ_ARG_%s=%v", strings.ToUpper(k), v))
+	}
+	if tp := trace.Traceparent(ctx); tp != "" {
+		env = append(env, "TRACEPARENT="+tp)
+	}
+
+	var binds []string
+	for _, m := range ct.Mounts {
+		binds = append(binds, absMount(os.ExpandEnv(m)))
+	}
+
+	createReq := map[string]interface{}{
+		"Image":        ct.Image,
+		"Cmd":          cmd,
+		"Env":          env,
+		"WorkingDir":   tool.Script.WorkingDir,
+		"User":         ct.User,
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Labels":       map[string]string{"run.gantz.tool": tool.Name},
+		"HostConfig": map[string]interface{}{
+			"Binds":       binds,
+			"NetworkMode": ct.Network,
+		},
+	}
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := client.do(ctx, http.MethodPost, "/containers/create", createReq, &created); err != nil {
+		return fail("Failed to create container: %v", err)
+	}
+
+	// Always remove the container, even after a timeout
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		client.do(cleanupCtx, http.MethodDelete, "/containers/"+created.ID+"?force=1&v=1", nil, nil)
+	}()
+
+	if err := client.do(ctx, http.MethodPost, "/containers/"+created.ID+"/start", nil, nil); err != nil {
+		return fail("Failed to start container: %v", err)
+	}
+
+	// Follow logs while the container runs
+	var stdout, stderr bytes.Buffer
+	stderrW, flushStderr := stderrWriter(ctx, &stderr)
+	logsDone := make(chan error, 1)
+	go func() {
+		resp, err := client.stream(ctx, http.MethodGet, "/containers/"+created.ID+"/logs?follow=1&stdout=1&stderr=1", nil)
+		if err != nil {
+			logsDone <- err
+			return
+		}
+		defer resp.Body.Close()
+		err = demuxLogs(resp.Body, &stdout, stderrW)
+		flushStderr()
+		logsDone <- err
+	}()
+
+	var waited struct {
+		StatusCode int `json:"StatusCode"`
+	}
+	waitErr := client.do(ctx, http.MethodPost, "/containers/"+created.ID+"/wait", nil, &waited)
+
+	if ctx.Err() != nil {
+		// Timed out (or cancelled): kill the container
+		killCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		client.do(killCtx, http.MethodPost, "/containers/"+created.ID+"/kill", nil, nil)
+		cancel()
+		<-logsDone
+		return &Result{
+			Output:   strings.TrimSpace(combineOutput(stdout.String(), stderr.String())),
+			ExitCode: -1,
+			Duration: time.Since(start),
+			Error:    ctx.Err(),
+		}
+	}
+	<-logsDone
+
+	result := &Result{
+		Output:   strings.TrimSpace(combineOutput(stdout.String(), stderr.String())),
+		ExitCode: waited.StatusCode,
+		Duration: time.Since(start),
+	}
+	if waitErr != nil {
+		result.ExitCode = -1
+		result.Error = waitErr
+	} else if waited.StatusCode != 0 {
+		result.Error = fmt.Errorf("container exited with status %d", waited.StatusCode)
+	}
+	return result
+}
+
+// demuxLogs splits Docker's multiplexed log stream into stdout and stderr
+func demuxLogs(r io.Reader, stdout, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		w := stdout
+		if header[0] == 2 {
+			w = stderr
+		}
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return err
+		}
+	}
+}
+
+// absMount makes the host side of a "host:container[:opts]" mount absolute
+func absMount(m string) string {
+	parts := strings.SplitN(m, ":", 2)
+	if len(parts) < 2 || filepath.IsAbs(parts[0]) {
+		return m
+	}
+	// Named volumes have no path separator
+	if !strings.HasPrefix(parts[0], ".") && !strings.Contains(parts[0], "/") {
+		return m
+	}
+	if abs, err := filepath.Abs(parts[0]); err == nil {
+		return abs + ":" + parts[1]
+	}
+	return m
+}
+
+// combineOutput joins stdout and stderr the way tool results present them
+func combineOutput(stdout, stderr string) string {
+	if stderr == "" {
+		return stdout
+	}
+	if stdout == "" {
+		return stderr
+	}
+	return stdout + "\n" + stderr
+}
@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+)
+
+// Describe renders what a tool call will do with the given arguments: the
+// expanded command line or the request it will send. Headers are left out
+// since they usually carry credentials.
+func Describe(tool *config.Tool, args map[string]interface{}) string {
+	switch {
+	case tool.IsHTTP():
+		method := tool.HTTP.Method
+		if method == "" {
+			method = "GET"
+		}
+		desc := method + " " + requestURL(tool, args)
+		if body, err := requestBody(tool, args); err == nil && body != nil {
+			desc += "\n\n" + string(body)
+		}
+		return desc
+
+	case tool.IsGraphQL():
+		desc := "POST " + tool.GraphQL.Endpoint + "\n\n" + strings.TrimSpace(tool.GraphQL.Query)
+		if vars := graphQLVariables(tool, args); len(vars) > 0 {
+			if data, err := json.MarshalIndent(vars, "", "  "); err == nil {
+				desc += "\n\nvariables: " + string(data)
+			}
+		}
+		return desc
+	}
+
+	var command string
+	if tool.Script.Shell != "" {
+		command = expandArgs(tool.Script.Shell, args)
+	} else {
+		parts := []string{tool.Script.Command}
+		for _, arg := range tool.Script.Args {
+			parts = append(parts, shellQuote(expandArgs(arg, args)))
+		}
+		command = strings.Join(parts, " ")
+	}
+
+	switch {
+	case tool.Script.SSH != nil:
+		return fmt.Sprintf("[ssh %s] %s", tool.Script.SSH.Host, command)
+	case tool.Script.Container != nil:
+		return fmt.Sprintf("[container %s] %s", tool.Script.Container.Image, command)
+	case tool.Script.WorkingDir != "":
+		return fmt.Sprintf("[in %s] %s", tool.Script.WorkingDir, command)
+	}
+	return command
+}
@@ -0,0 +1,216 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/metrics"
+)
+
+// GraphQLExecutor runs GraphQL operations for tools
+type GraphQLExecutor struct {
+	client *http.Client
+}
+
+// NewGraphQLExecutor creates a new GraphQL executor
+func NewGraphQLExecutor() *GraphQLExecutor {
+	return &GraphQLExecutor{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []graphQLError  `json:"errors"`
+}
+
+type graphQLError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path"`
+}
+
+// Execute sends a GraphQL operation for a tool
+func (e *GraphQLExecutor) Execute(ctx context.Context, tool *config.Tool, args map[string]interface{}) *Result {
+	start := time.Now()
+	defer func() {
+		metrics.ExecutionDuration.With("graphql", tool.Name).Observe(time.Since(start).Seconds())
+	}()
+
+	// Parse timeout
+	timeout := 30 * time.Second
+	if tool.GraphQL.Timeout != "" {
+		if d, err := time.ParseDuration(tool.GraphQL.Timeout); err == nil {
+			timeout = d
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	payload, err := json.Marshal(graphQLRequest{
+		Query:     tool.GraphQL.Query,
+		Variables: graphQLVariables(tool, args),
+	})
+	if err != nil {
+		return &Result{
+			Output:   fmt.Sprintf("Failed to encode request: %v", err),
+			ExitCode: -1,
+			Duration: time.Since(start),
+			Error:    err,
+		}
+	}
+
+	endpoint := expandEnv(tool, expandArgs(tool.GraphQL.Endpoint, args))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return &Result{
+			Output:   fmt.Sprintf("Failed to create request: %v", err),
+			ExitCode: -1,
+			Duration: time.Since(start),
+			Error:    err,
+		}
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for key, value := range tool.GraphQL.Headers {
+		req.Header.Set(key, expandEnv(tool, expandArgs(value, args)))
+	}
+
+	resp, err := doRequest(e.client, tool, req)
+	if err != nil {
+		return &Result{
+			Output:   fmt.Sprintf("Request failed: %v", err),
+			ExitCode: -1,
+			Duration: time.Since(start),
+			Error:    err,
+		}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &Result{
+			Output:   fmt.Sprintf("Failed to read response: %v", err),
+			ExitCode: -1,
+			Duration: time.Since(start),
+			Error:    err,
+		}
+	}
+
+	var gqlResp graphQLResponse
+	if err := json.Unmarshal(body, &gqlResp); err != nil {
+		// Not a GraphQL response (e.g. a proxy error page), return it as-is
+		exitCode := 0
+		if resp.StatusCode >= 400 {
+			exitCode = 1
+		}
+		return &Result{
+			Output:   strings.TrimSpace(string(body)),
+			ExitCode: exitCode,
+			Duration: time.Since(start),
+		}
+	}
+
+	output := ""
+	hasData := len(gqlResp.Data) > 0 && string(gqlResp.Data) != "null"
+	if hasData {
+		output = formatJSON(gqlResp.Data)
+		if tool.GraphQL.ExtractJSON != "" {
+			if extracted, err := extractJSONPath(gqlResp.Data, tool.GraphQL.ExtractJSON); err == nil {
+				output = extracted
+			}
+		}
+	}
+
+	exitCode := 0
+	if len(gqlResp.Errors) > 0 {
+		exitCode = 1
+		var sb strings.Builder
+		sb.WriteString("GraphQL errors:")
+		for _, gqlErr := range gqlResp.Errors {
+			sb.WriteString("\n- ")
+			sb.WriteString(gqlErr.Message)
+			if len(gqlErr.Path) > 0 {
+				sb.WriteString(fmt.Sprintf(" (at %s)", formatGraphQLPath(gqlErr.Path)))
+			}
+		}
+		if hasData {
+			sb.WriteString("\n\nPartial data:\n")
+			sb.WriteString(output)
+		}
+		output = sb.String()
+	} else if resp.StatusCode >= 400 {
+		exitCode = 1
+	}
+
+	return &Result{
+		Output:   strings.TrimSpace(output),
+		ExitCode: exitCode,
+		Duration: time.Since(start),
+	}
+}
+
+// graphQLVariables builds the variables object for a request. Without an
+// explicit mapping, tool arguments are passed through as variables. A mapping
+// value that is exactly "{{param}}" keeps the argument's JSON type; anything
+// else is expanded as a string template.
+func graphQLVariables(tool *config.Tool, args map[string]interface{}) map[string]interface{} {
+	if len(tool.GraphQL.Variables) == 0 {
+		return args
+	}
+
+	vars := make(map[string]interface{}, len(tool.GraphQL.Variables))
+	for name, tmpl := range tool.GraphQL.Variables {
+		trimmed := strings.TrimSpace(tmpl)
+		if strings.HasPrefix(trimmed, "{{") && strings.HasSuffix(trimmed, "}}") && strings.Count(trimmed, "{{") == 1 {
+			param := strings.TrimSpace(trimmed[2 : len(trimmed)-2])
+			if v, ok := args[param]; ok {
+				vars[name] = v
+			}
+			continue
+		}
+		vars[name] = expandEnv(tool, expandArgs(tmpl, args))
+	}
+	return vars
+}
+
+// formatGraphQLPath renders an error path like ["user", 0, "name"] as user[0].name
+func formatGraphQLPath(path []interface{}) string {
+	var sb strings.Builder
+	for i, seg := range path {
+		switch v := seg.(type) {
+		case float64:
+			sb.WriteString(fmt.Sprintf("[%d]", int(v)))
+		default:
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(fmt.Sprintf("%v", v))
+		}
+	}
+	return sb.String()
+}
+
+// formatJSON pretty-prints a raw JSON value
+func formatJSON(data json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
@@ -1,16 +1,21 @@
 package executor
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
-	"os"
+	neturl "net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/metrics"
+	"github.com/gantz-ai/gantz-cli/internal/trace"
 )
 
 // HTTPExecutor runs HTTP requests for tools
@@ -27,9 +32,110 @@ func NewHTTPExecutor() *HTTPExecutor {
 	}
 }
 
+// requestURL expands a tool's URL and appends query parameters, skipping
+// those whose arguments were not supplied
+func requestURL(tool *config.Tool, args map[string]interface{}) string {
+	url := expandArgs(tool.HTTP.URL, pathEscapeArgs(tool, args))
+	url = expandEnv(tool, url)
+
+	if len(tool.HTTP.Query) > 0 {
+		query := neturl.Values{}
+		for key, value := range tool.HTTP.Query {
+			expandedValue := expandArgs(value, args)
+			if hasPlaceholder(expandedValue) {
+				continue
+			}
+			query.Set(key, expandEnv(tool, expandedValue))
+		}
+		if encoded := query.Encode(); encoded != "" {
+			if strings.Contains(url, "?") {
+				url += "&" + encoded
+			} else {
+				url += "?" + encoded
+			}
+		}
+	}
+	return url
+}
+
+// pathEscapeArgs returns args with the tool's path parameters escaped, so a
+// value can't add or climb segments, or start a query or fragment
+func pathEscapeArgs(tool *config.Tool, args map[string]interface{}) map[string]interface{} {
+	if len(tool.HTTP.PathParams) == 0 {
+		return args
+	}
+	escaped := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		escaped[k] = v
+	}
+	for _, name := range tool.HTTP.PathParams {
+		v, ok := args[name]
+		if !ok {
+			continue
+		}
+		segment := neturl.PathEscape(fmt.Sprintf("%v", v))
+		if segment == "." || segment == ".." {
+			segment = strings.ReplaceAll(segment, ".", "%2E")
+		}
+		escaped[name] = segment
+	}
+	return escaped
+}
+
+// requestBody builds a tool's request body, or nil if it has none
+func requestBody(tool *config.Tool, args map[string]interface{}) ([]byte, error) {
+	if tool.HTTP.Body != "" {
+		body := expandArgs(tool.HTTP.Body, args)
+		return []byte(expandEnv(tool, body)), nil
+	}
+	if len(tool.HTTP.JSONBody) > 0 {
+		fields := make(map[string]interface{}, len(tool.HTTP.JSONBody))
+		for _, name := range tool.HTTP.JSONBody {
+			if v, ok := args[name]; ok {
+				fields[name] = v
+			}
+		}
+		return json.Marshal(fields)
+	}
+	return nil, nil
+}
+
+// doRequest sends a tool's request with the caller's traceparent and
+// records its latency until the response headers arrive
+func doRequest(client *http.Client, tool *config.Tool, req *http.Request) (*http.Response, error) {
+	ctx, span := trace.Start(req.Context(), req.Method, trace.Client)
+	defer span.End()
+	span.SetAttr("gantz.tool", tool.Name)
+	span.SetAttr("http.request.method", req.Method)
+	span.SetAttr("url.full", (&neturl.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: req.URL.Path}).String()) // No query, it may hold keys
+	req = req.WithContext(ctx)
+	if tp := trace.Traceparent(ctx); tp != "" {
+		req.Header.Set("traceparent", tp)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+		span.SetAttr("http.response.status_code", resp.StatusCode)
+		if resp.StatusCode >= 400 {
+			span.Fail(resp.Status)
+		}
+	} else {
+		span.Fail("request failed")
+	}
+	metrics.HTTPRequestDuration.With(tool.Name, code).Observe(time.Since(start).Seconds())
+	slog.Debug("HTTP request", "tool", tool.Name, "method", req.Method, "host", req.URL.Host, "status", code, "duration", time.Since(start))
+	return resp, err
+}
+
 // Execute makes an HTTP request for a tool
 func (e *HTTPExecutor) Execute(ctx context.Context, tool *config.Tool, args map[string]interface{}) *Result {
 	start := time.Now()
+	defer func() {
+		metrics.ExecutionDuration.With("http", tool.Name).Observe(time.Since(start).Seconds())
+	}()
 
 	// Parse timeout
 	timeout := 30 * time.Second
@@ -42,9 +148,8 @@ func (e *HTTPExecutor) Execute(ctx context.Context, tool *config.Tool, args map[
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Expand URL with arguments
-	url := expandArgs(tool.HTTP.URL, args)
-	url = os.ExpandEnv(url)
+	// Expand URL and query parameters with arguments
+	url := requestURL(tool, args)
 
 	// Determine method (default to GET)
 	method := tool.HTTP.Method
@@ -54,10 +159,17 @@ func (e *HTTPExecutor) Execute(ctx context.Context, tool *config.Tool, args map[
 
 	// Prepare body
 	var bodyReader io.Reader
-	if tool.HTTP.Body != "" {
-		body := expandArgs(tool.HTTP.Body, args)
-		body = os.ExpandEnv(body)
-		bodyReader = strings.NewReader(body)
+	reqBody, err := requestBody(tool, args)
+	if err != nil {
+		return &Result{
+			Output:   fmt.Sprintf("Failed to encode body: %v", err),
+			ExitCode: -1,
+			Duration: time.Since(start),
+			Error:    err,
+		}
+	}
+	if reqBody != nil {
+		bodyReader = bytes.NewReader(reqBody)
 	}
 
 	// Create request
@@ -74,7 +186,7 @@ func (e *HTTPExecutor) Execute(ctx context.Context, tool *config.Tool, args map[
 	// Set headers
 	for key, value := range tool.HTTP.Headers {
 		expandedValue := expandArgs(value, args)
-		expandedValue = os.ExpandEnv(expandedValue)
+		expandedValue = expandEnv(tool, expandedValue)
 		req.Header.Set(key, expandedValue)
 	}
 
@@ -84,7 +196,7 @@ func (e *HTTPExecutor) Execute(ctx context.Context, tool *config.Tool, args map[
 	}
 
 	// Execute request
-	resp, err := e.client.Do(req)
+	resp, err := doRequest(e.client, tool, req)
 	if err != nil {
 		return &Result{
 			Output:   fmt.Sprintf("Request failed: %v", err),
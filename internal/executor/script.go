@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
 	"strings"
 	"time"
 
 	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/metrics"
+	"github.com/gantz-ai/gantz-cli/internal/trace"
 )
 
 // Result represents script execution result
@@ -22,16 +26,56 @@ type Result struct {
 }
 
 // Executor runs scripts for tools
-type Executor struct{}
+type Executor struct {
+	ssh        *sshPool
+	containers *containerClients
+}
 
 // NewExecutor creates a new script executor
 func NewExecutor() *Executor {
-	return &Executor{}
+	return &Executor{
+		ssh:        newSSHPool(),
+		containers: &containerClients{},
+	}
+}
+
+// Close releases connections kept open between calls
+func (e *Executor) Close() {
+	e.ssh.Close()
+	e.containers.Close()
+}
+
+type extraEnvKey struct{}
+
+// WithEnv returns a context that adds KEY=value entries to the environment of
+// scripts run on this host
+func WithEnv(ctx context.Context, env []string) context.Context {
+	return context.WithValue(ctx, extraEnvKey{}, env)
 }
 
 // Execute runs a tool's script with the given arguments
-func (e *Executor) Execute(ctx context.Context, tool *config.Tool, args map[string]interface{}) *Result {
+func (e *Executor) Execute(ctx context.Context, tool *config.Tool, args map[string]interface{}) (result *Result) {
 	start := time.Now()
+	kind := "script"
+	switch {
+	case tool.Script.Container != nil:
+		kind = "container"
+	case tool.Script.SSH != nil:
+		kind = "ssh"
+	}
+	ctx, span := trace.Start(ctx, "execute "+kind, trace.Internal)
+	span.SetAttr("gantz.tool", tool.Name)
+	span.SetAttr("gantz.executor", kind)
+	slog.Debug("Running script", "tool", tool.Name, "executor", kind)
+	defer func() {
+		metrics.ExecutionDuration.With(kind, tool.Name).Observe(time.Since(start).Seconds())
+		slog.Debug("Script finished", "tool", tool.Name, "executor", kind, "exit_code", result.ExitCode, "duration", time.Since(start))
+		span.SetAttr("process.exit.code", result.ExitCode)
+		if result.ExitCode != 0 {
+			span.Fail(fmt.Sprintf("exit code %d", result.ExitCode))
+		}
+		span.End()
+	}()
 
 	// Parse timeout
 	timeout := 30 * time.Second
@@ -44,6 +88,13 @@ func (e *Executor) Execute(ctx context.Context, tool *config.Tool, args map[stri
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	if tool.Script.Container != nil {
+		return e.executeContainer(ctx, tool, args, start)
+	}
+	if tool.Script.SSH != nil {
+		return e.executeSSH(ctx, tool, args, start)
+	}
+
 	var cmd *exec.Cmd
 
 	if tool.Script.Shell != "" {
@@ -68,7 +119,7 @@ func (e *Executor) Execute(ctx context.Context, tool *config.Tool, args map[stri
 	// Set environment
 	cmd.Env = os.Environ()
 	for k, v := range tool.Environment {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, os.ExpandEnv(v)))
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, expandEnv(tool, v)))
 	}
 
 	// Add args as environment variables
@@ -76,26 +127,27 @@ func (e *Executor) Execute(ctx context.Context, tool *config.Tool, args map[stri
 		envKey := fmt.Sprintf("GANTZ_ARG_%s", strings.ToUpper(k))
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%v", envKey, v))
 	}
+	if env, ok := ctx.Value(extraEnvKey{}).([]string); ok {
+		cmd.Env = append(cmd.Env, env...)
+	}
+	if tp := trace.Traceparent(ctx); tp != "" {
+		cmd.Env = append(cmd.Env, "TRACEPARENT="+tp)
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	var flushStderr func()
+	cmd.Stderr, flushStderr = stderrWriter(ctx, &stderr)
 
 	err := cmd.Run()
+	flushStderr()
 
-	result := &Result{
+	result = &Result{
 		Duration: time.Since(start),
 	}
 
 	// Combine stdout and stderr
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		if output != "" {
-			output += "\n"
-		}
-		output += stderr.String()
-	}
-	result.Output = strings.TrimSpace(output)
+	result.Output = strings.TrimSpace(combineOutput(stdout.String(), stderr.String()))
 
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
@@ -131,3 +183,19 @@ func expandArgs(template string, args map[string]interface{}) string {
 	}
 	return result
 }
+
+// expandEnv expands ${VAR} references at call time, unless that was done
+// when the tool's secrets were resolved
+func expandEnv(tool *config.Tool, s string) string {
+	if tool.EnvExpanded {
+		return s
+	}
+	return os.ExpandEnv(s)
+}
+
+var placeholderPattern = regexp.MustCompile(`\{\{[^{}]+\}\}`)
+
+// hasPlaceholder reports whether a string still contains unexpanded {{arg}} placeholders
+func hasPlaceholder(s string) bool {
+	return placeholderPattern.MatchString(s)
+}
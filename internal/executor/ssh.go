@@ -0,0 +1,395 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/trace"
+)
+
+// sshPool keeps SSH connections open so repeated tool calls reuse them
+type sshPool struct {
+	mu      sync.Mutex
+	clients map[string]*ssh.Client
+	dialing map[string]*sshDial
+}
+
+// sshDial is a connection being dialed; calls for the same key wait for it
+// instead of dialing again
+type sshDial struct {
+	done   chan struct{}
+	client *ssh.Client
+	err    error
+}
+
+func newSSHPool() *sshPool {
+	return &sshPool{clients: make(map[string]*ssh.Client), dialing: make(map[string]*sshDial)}
+}
+
+// session opens a session on a pooled connection, redialing once if the
+// cached connection has gone away
+func (p *sshPool) session(ctx context.Context, cfg *config.SSHConfig) (*ssh.Session, error) {
+	key := fmt.Sprintf("%s@%s|%s|%s|%s", sshUser(cfg.User), sshAddr(cfg.Host), cfg.KeyFile, cfg.KnownHosts, cfg.JumpHost)
+
+	client, err := p.client(ctx, key, cfg)
+	if err != nil {
+		return nil, err
+	}
+	session, err := client.NewSession()
+	if err == nil {
+		return session, nil
+	}
+
+	p.drop(key, client)
+	client, err = p.client(ctx, key, cfg)
+	if err != nil {
+		return nil, err
+	}
+	session, err = client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("ssh session: %w", err)
+	}
+	return session, nil
+}
+
+// client returns the pooled connection for key, dialing it without holding
+// the lock so slow hosts don't hold up calls to other hosts
+func (p *sshPool) client(ctx context.Context, key string, cfg *config.SSHConfig) (*ssh.Client, error) {
+	p.mu.Lock()
+	if client, ok := p.clients[key]; ok {
+		p.mu.Unlock()
+		return client, nil
+	}
+	if d, ok := p.dialing[key]; ok {
+		p.mu.Unlock()
+		select {
+		case <-d.done:
+			return d.client, d.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	d := &sshDial{done: make(chan struct{})}
+	p.dialing[key] = d
+	p.mu.Unlock()
+
+	client, err := dialSSH(ctx, cfg)
+
+	p.mu.Lock()
+	delete(p.dialing, key)
+	if err == nil {
+		p.clients[key] = client
+	}
+	p.mu.Unlock()
+	d.client, d.err = client, err
+	close(d.done)
+	if err != nil {
+		return nil, err
+	}
+
+	// Forget the connection once the server closes it
+	go func() {
+		client.Wait()
+		p.drop(key, client)
+	}()
+	return client, nil
+}
+
+func (p *sshPool) drop(key string, client *ssh.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.clients[key] == client {
+		delete(p.clients, key)
+	}
+	client.Close()
+}
+
+// Close closes all pooled connections
+func (p *sshPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, client := range p.clients {
+		client.Close()
+		delete(p.clients, key)
+	}
+}
+
+// dialSSH connects to the target host, through the jump host if configured
+func dialSSH(ctx context.Context, cfg *config.SSHConfig) (*ssh.Client, error) {
+	// One agent connection serves the handshakes with the jump host and the
+	// target; it isn't needed once they are done
+	var keyAgent agent.Agent
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" && cfg.KeyFile == "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			defer conn.Close()
+			keyAgent = agent.NewClient(conn)
+		}
+	}
+
+	target, err := sshClientConfig(cfg.User, cfg, keyAgent)
+	if err != nil {
+		return nil, err
+	}
+	addr := sshAddr(cfg.Host)
+
+	if cfg.JumpHost == "" {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("ssh %s: %w", addr, err)
+		}
+		return newSSHClient(conn, addr, target)
+	}
+
+	jumpUser, jumpHost := "", cfg.JumpHost
+	if i := strings.LastIndex(jumpHost, "@"); i >= 0 {
+		jumpUser, jumpHost = jumpHost[:i], jumpHost[i+1:]
+	}
+	jumpCfg, err := sshClientConfig(jumpUser, cfg, keyAgent)
+	if err != nil {
+		return nil, err
+	}
+	jumpAddr := sshAddr(jumpHost)
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", jumpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh jump host %s: %w", jumpAddr, err)
+	}
+	jump, err := newSSHClient(conn, jumpAddr, jumpCfg)
+	if err != nil {
+		return nil, fmt.Errorf("ssh jump host: %w", err)
+	}
+
+	tunneled, err := jump.Dial("tcp", addr)
+	if err != nil {
+		jump.Close()
+		return nil, fmt.Errorf("ssh %s via %s: %w", addr, jumpAddr, err)
+	}
+	client, err := newSSHClient(tunneled, addr, target)
+	if err != nil {
+		jump.Close()
+		return nil, err
+	}
+	// Close the jump connection together with the target connection
+	go func() {
+		client.Wait()
+		jump.Close()
+	}()
+	return client, nil
+}
+
+func newSSHClient(conn net.Conn, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh %s: %w", addr, err)
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// sshClientConfig builds authentication and host key checking for a user,
+// trying the keys in keyAgent if it is set
+func sshClientConfig(username string, cfg *config.SSHConfig, keyAgent agent.Agent) (*ssh.ClientConfig, error) {
+	knownHostsPath := cfg.KnownHosts
+	if knownHostsPath == "" {
+		knownHostsPath = filepath.Join(sshDir(), "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(expandHome(knownHostsPath))
+	if err != nil {
+		return nil, fmt.Errorf("ssh known_hosts: %w", err)
+	}
+
+	var auth []ssh.AuthMethod
+	if keyAgent != nil {
+		auth = append(auth, ssh.PublicKeysCallback(keyAgent.Signers))
+	}
+
+	keyFiles := []string{cfg.KeyFile}
+	if cfg.KeyFile == "" {
+		keyFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+		for i, name := range keyFiles {
+			keyFiles[i] = filepath.Join(sshDir(), name)
+		}
+	}
+	var signers []ssh.Signer
+	for _, path := range keyFiles {
+		data, err := os.ReadFile(expandHome(path))
+		if err != nil {
+			if cfg.KeyFile != "" {
+				return nil, fmt.Errorf("ssh key_file: %w", err)
+			}
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			var passErr *ssh.PassphraseMissingError
+			if errors.As(err, &passErr) && cfg.KeyFile == "" {
+				continue // Encrypted default keys are expected to be in the agent
+			}
+			return nil, fmt.Errorf("ssh key %s: %w (load encrypted keys into ssh-agent instead)", path, err)
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("ssh: no credentials (set ssh.key_file or start ssh-agent)")
+	}
+
+	return &ssh.ClientConfig{
+		User:            sshUser(username),
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         15 * time.Second,
+	}, nil
+}
+
+// executeSSH runs a tool's script on a remote host
+func (e *Executor) executeSSH(ctx context.Context, tool *config.Tool, args map[string]interface{}, start time.Time) *Result {
+	session, err := e.ssh.session(ctx, tool.Script.SSH)
+	if err != nil {
+		return &Result{
+			Output:   fmt.Sprintf("SSH connection failed: %v", err),
+			ExitCode: -1,
+			Duration: time.Since(start),
+			Error:    err,
+		}
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	var flushStderr func()
+	session.Stderr, flushStderr = stderrWriter(ctx, &stderr)
+
+	done := make(chan error, 1)
+	go func() {
+		err := session.Run(remoteCommand(ctx, tool, args))
+		flushStderr()
+		done <- err
+	}()
+
+	var runErr error
+	select {
+	case runErr = <-done:
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+		<-done
+		return &Result{
+			Output:   strings.TrimSpace(combineOutput(stdout.String(), stderr.String())),
+			ExitCode: -1,
+			Duration: time.Since(start),
+			Error:    ctx.Err(),
+		}
+	}
+
+	result := &Result{
+		Output:   strings.TrimSpace(combineOutput(stdout.String(), stderr.String())),
+		Duration: time.Since(start),
+	}
+	if runErr != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(runErr, &exitErr) {
+			result.ExitCode = exitErr.ExitStatus()
+		} else {
+			result.ExitCode = -1
+		}
+		result.Error = runErr
+	}
+	return result
+}
+
+// remoteCommand builds the command line run by the remote shell. Environment
+// variables are exported inline because sshd usually rejects setenv requests.
+func remoteCommand(ctx context.Context, tool *config.Tool, args map[string]interface{}) string {
+	var exports []string
+	for k, v := range tool.Environment {
+		exports = append(exports, fmt.Sprintf("%s=%s", k, shellQuote(expandEnv(tool, v))))
+	}
+	// Argument names come from the client and end up in shell code, so only
+	// declared parameters with plain names are exported
+	for _, param := range tool.Parameters {
+		v, ok := args[param.Name]
+		if !ok || !envNamePattern.MatchString(param.Name) {
+			continue
+		}
+		exports = append(exports, fmt.Sprintf("GANTZ_ARG_%s=%s", strings.ToUpper(param.Name), shellQuote(fmt.Sprintf("%v", v))))
+	}
+	if tp := trace.Traceparent(ctx); tp != "" {
+		exports = append(exports, "TRACEPARENT="+shellQuote(tp))
+	}
+	sort.Strings(exports)
+
+	var sb strings.Builder
+	if len(exports) > 0 {
+		sb.WriteString("export " + strings.Join(exports, " ") + "; ")
+	}
+	if tool.Script.WorkingDir != "" {
+		sb.WriteString("cd " + shellQuote(tool.Script.WorkingDir) + " && ")
+	}
+
+	if tool.Script.Shell != "" {
+		sb.WriteString(expandArgs(tool.Script.Shell, args))
+	} else {
+		parts := []string{shellQuote(tool.Script.Command)}
+		for _, arg := range tool.Script.Args {
+			parts = append(parts, shellQuote(expandArgs(arg, args)))
+		}
+		sb.WriteString(strings.Join(parts, " "))
+	}
+	return sb.String()
+}
+
+// envNamePattern matches names that are safe to use in a shell variable name
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// shellQuote quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func sshAddr(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, "22")
+}
+
+func sshUser(name string) string {
+	if name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func sshDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ssh")
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
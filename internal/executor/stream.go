@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+)
+
+type stderrHandlerKey struct{}
+
+// WithStderrHandler returns a context that makes executors report each line
+// a script writes to stderr while it runs
+func WithStderrHandler(ctx context.Context, fn func(line string)) context.Context {
+	return context.WithValue(ctx, stderrHandlerKey{}, fn)
+}
+
+// stderrWriter returns w, teed into a line writer if the context carries a
+// stderr handler. The returned flush function reports a trailing partial line.
+func stderrWriter(ctx context.Context, w io.Writer) (io.Writer, func()) {
+	fn, ok := ctx.Value(stderrHandlerKey{}).(func(line string))
+	if !ok || fn == nil {
+		return w, func() {}
+	}
+	lw := &lineWriter{fn: fn}
+	return io.MultiWriter(w, lw), lw.flush
+}
+
+// lineWriter calls fn for every complete line written to it
+type lineWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	fn  func(line string)
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	lw.buf.Write(p)
+	for {
+		i := bytes.IndexByte(lw.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(lw.buf.Next(i+1)), "\r\n")
+		if line != "" {
+			lw.fn(line)
+		}
+	}
+	return len(p), nil
+}
+
+func (lw *lineWriter) flush() {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if line := strings.TrimSpace(lw.buf.String()); line != "" {
+		lw.fn(line)
+	}
+	lw.buf.Reset()
+}
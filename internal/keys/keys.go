@@ -0,0 +1,277 @@
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretPrefix marks gantz API keys so they are easy to recognize in configs
+const secretPrefix = "gtzk_"
+
+// Errors returned by Store.Verify
+var (
+	ErrUnknown = errors.New("unknown API key")
+	ErrExpired = errors.New("API key has expired")
+	ErrRevoked = errors.New("API key has been revoked")
+)
+
+// Key is a named API key. Only a hash of the secret is stored.
+type Key struct {
+	ID      string     `json:"id"`
+	Label   string     `json:"label"`
+	Hash    string     `json:"hash"`   // SHA-256 of the secret, hex encoded
+	Prefix  string     `json:"prefix"` // Start of the secret, to recognize it
+	Tools   []string   `json:"tools,omitempty"`
+	Tags    []string   `json:"tags,omitempty"`
+	Created time.Time  `json:"created"`
+	Expires *time.Time `json:"expires,omitempty"`
+	Revoked *time.Time `json:"revoked,omitempty"`
+}
+
+// Unrestricted reports whether the key may call every tool
+func (k *Key) Unrestricted() bool {
+	return len(k.Tools) == 0 && len(k.Tags) == 0
+}
+
+// Allows reports whether the key may see and call a tool. Tool entries may
+// be glob patterns such as "github_*".
+func (k *Key) Allows(tool string, tags []string) bool {
+	if k.Unrestricted() {
+		return true
+	}
+	for _, pattern := range k.Tools {
+		if ok, _ := path.Match(pattern, tool); ok {
+			return true
+		}
+	}
+	for _, allowed := range k.Tags {
+		for _, tag := range tags {
+			if tag == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Status describes whether the key can be used
+func (k *Key) Status() string {
+	switch {
+	case k.Revoked != nil:
+		return "revoked"
+	case k.Expires != nil && time.Now().After(*k.Expires):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+// Store is a file of API keys. It rereads the file when it changes, so keys
+// created or revoked while gantz runs take effect immediately.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	keys    []*Key
+	modTime time.Time
+	existed bool // The file has existed since the store was opened
+}
+
+// DefaultPath returns the key file in the user's config directory
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "gantz-keys.json"
+	}
+	return filepath.Join(dir, "gantz", "keys.json")
+}
+
+// Open reads a key store; a missing file is an empty store
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path returns the file the store is kept in
+func (s *Store) Path() string {
+	return s.path
+}
+
+// load reads the file if it changed since it was last read; callers hold s.mu
+func (s *Store) load() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.keys = nil
+		s.modTime = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) && s.keys != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var keys []*Key
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	s.keys = keys
+	s.modTime = info.ModTime()
+	s.existed = true
+	return nil
+}
+
+// save writes the store; callers hold s.mu
+func (s *Store) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	s.existed = true
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// Keys returns all keys, including expired and revoked ones
+func (s *Store) Keys() ([]*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return append([]*Key(nil), s.keys...), nil
+}
+
+// Active reports whether requests must present a key: once the store file
+// exists, even if every key in it expired, was revoked or was removed.
+// Revoking a leaked key must never open the server to everyone.
+func (s *Store) Active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		// An unreadable store must not turn authentication off
+		return true
+	}
+	return s.existed
+}
+
+// Create adds a key and returns its secret, which is not stored and cannot
+// be shown again. A zero ttl means the key does not expire.
+func (s *Store) Create(label string, tools, tags []string, ttl time.Duration) (string, *Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return "", nil, err
+	}
+	for _, k := range s.keys {
+		if label != "" && k.Label == label && k.Revoked == nil {
+			return "", nil, fmt.Errorf("a key labelled %q already exists", label)
+		}
+	}
+
+	secret := secretPrefix + randomHex(24)
+	key := &Key{
+		ID:      randomHex(4),
+		Label:   label,
+		Hash:    hashSecret(secret),
+		Prefix:  secret[:len(secretPrefix)+4],
+		Tools:   tools,
+		Tags:    tags,
+		Created: time.Now().UTC().Truncate(time.Second),
+	}
+	if ttl > 0 {
+		expires := key.Created.Add(ttl)
+		key.Expires = &expires
+	}
+
+	s.keys = append(s.keys, key)
+	if err := s.save(); err != nil {
+		return "", nil, err
+	}
+	return secret, key, nil
+}
+
+// Revoke disables the key with the given ID or label
+func (s *Store) Revoke(idOrLabel string) (*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	for _, k := range s.keys {
+		if (k.ID == idOrLabel || k.Label == idOrLabel) && k.Revoked == nil {
+			now := time.Now().UTC().Truncate(time.Second)
+			k.Revoked = &now
+			return k, s.save()
+		}
+	}
+	return nil, fmt.Errorf("no active key with ID or label %q", idOrLabel)
+}
+
+// Verify returns the key a secret belongs to if it can be used
+func (s *Store) Verify(secret string) (*Key, error) {
+	if !strings.HasPrefix(secret, secretPrefix) {
+		return nil, ErrUnknown
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	// Comparing hashes doesn't reveal anything about the secret
+	hash := hashSecret(secret)
+	for _, k := range s.keys {
+		if k.Hash != hash {
+			continue
+		}
+		switch k.Status() {
+		case "revoked":
+			return nil, ErrRevoked
+		case "expired":
+			return nil, ErrExpired
+		}
+		return k, nil
+	}
+	return nil, ErrUnknown
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Options configure the process logger
+type Options struct {
+	Format string // pretty (default), text or json
+	Level  string // debug, info (default), warn or error
+	File   string // Append to this file instead of writing to the terminal
+}
+
+// Setup makes a logger for opts the slog default and returns a function
+// that closes the log file
+func Setup(opts Options) (closeFn func() error, err error) {
+	var level slog.Level
+	if opts.Level != "" {
+		if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+			return nil, fmt.Errorf("unknown log level '%s': use debug, info, warn or error", opts.Level)
+		}
+	}
+
+	// Pretty output goes to stdout with the banner, like it always has; the
+	// machine formats go to stderr so stdout stays clean
+	format := strings.ToLower(opts.Format)
+	var w io.Writer = os.Stdout
+	closeFn = func() error { return nil }
+	color := true
+	if format == "text" || format == "json" {
+		w = os.Stderr
+	}
+	if opts.File != "" {
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("open log file: %w", err)
+		}
+		w, closeFn, color = f, f.Close, false
+	}
+
+	var handler slog.Handler
+	switch format {
+	case "", "pretty":
+		handler = newPrettyHandler(w, level, color)
+	case "text":
+		handler = slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})
+	case "json":
+		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	default:
+		closeFn()
+		return nil, fmt.Errorf("unknown log format '%s': use pretty, text or json", opts.Format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return closeFn, nil
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// prettyHandler writes the indented, colored lines gantz run has always
+// shown, with attributes appended as dim key=value pairs:
+//
+//	● Session started client=claude-ai protocol=2025-06-18
+//	! Rate limited client=203.0.113.7 tool=deploy retry_in=2s
+type prettyHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Leveler
+	color bool
+
+	attrs  string // Preformatted attributes from WithAttrs
+	prefix string // Group names from WithGroup, dot-separated
+}
+
+func newPrettyHandler(w io.Writer, level slog.Leveler, useColor bool) *prettyHandler {
+	return &prettyHandler{mu: &sync.Mutex{}, w: w, level: level, color: useColor}
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *prettyHandler) Handle(_ context.Context, r slog.Record) error {
+	symbol, paint := "●", (*color.Color)(nil)
+	switch {
+	case r.Level >= slog.LevelError:
+		symbol, paint = "✗", color.New(color.FgHiRed)
+	case r.Level >= slog.LevelWarn:
+		symbol, paint = "!", color.New(color.FgHiYellow)
+	case r.Level < slog.LevelInfo:
+		symbol, paint = "·", color.New(color.Faint)
+	}
+	faint := color.New(color.Faint)
+	if !h.color {
+		faint.DisableColor()
+		paint = nil
+	}
+	sprint := fmt.Sprint
+	if paint != nil {
+		sprint = paint.Sprint
+	}
+
+	var attrs strings.Builder
+	attrs.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&attrs, h.prefix, a)
+		return true
+	})
+
+	line := "  " + sprint(symbol) + " " + sprint(r.Message)
+	if attrs.Len() > 0 {
+		line += faint.Sprint(attrs.String())
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line+"\n")
+	return err
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, a := range attrs {
+		writeAttr(&b, h.prefix, a)
+	}
+	h2 := *h
+	h2.attrs = b.String()
+	return &h2
+}
+
+func (h *prettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// writeAttr appends " key=value", flattening groups into dotted keys
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			writeAttr(b, prefix, ga)
+		}
+		return
+	}
+	value := a.Value.String()
+	if a.Value.Kind() == slog.KindAny {
+		value = fmt.Sprint(a.Value.Any())
+	}
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(" " + prefix + a.Key + "=" + value)
+}
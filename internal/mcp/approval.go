@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+// defaultConfirmTimeout is how long a tool call waits for approval
+const defaultConfirmTimeout = 2 * time.Minute
+
+// ApprovalRequest describes a tool call waiting for a human to approve it
+type ApprovalRequest struct {
+	Tool      string
+	Action    string // The expanded command or request
+	Arguments map[string]interface{}
+}
+
+// Approver asks a human whether a tool call may run. It returns an error
+// when no answer is given before ctx is done.
+type Approver interface {
+	Approve(ctx context.Context, req *ApprovalRequest) (bool, error)
+}
+
+// SetApprover sets who is asked to approve tool calls that need confirmation
+func (s *Server) SetApprover(a Approver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.approver = a
+}
+
+// requestApproval asks the approver about a tool call and returns why it was
+// refused, or "" if it may run
+func (s *Server) requestApproval(req *ApprovalRequest) string {
+	s.mu.RLock()
+	approver := s.approver
+	timeout := defaultConfirmTimeout
+	if s.config.Confirm.Timeout != "" {
+		timeout, _ = time.ParseDuration(s.config.Confirm.Timeout)
+	}
+	s.mu.RUnlock()
+
+	if approver == nil {
+		return "this tool requires human approval, but nobody is available to approve it"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	approved, err := approver.Approve(ctx, req)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Sprintf("no approval was given within %v", timeout)
+	case err != nil:
+		return fmt.Sprintf("approval failed: %v", err)
+	case !approved:
+		return "the user denied this tool call"
+	}
+	return ""
+}
+
+// deniedResponse tells the agent that a tool call was not run
+func deniedResponse(req *tunnel.MCPRequest, tool, reason string) *tunnel.MCPResponse {
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"content": []map[string]interface{}{
+				{"type": "text", "text": fmt.Sprintf("Tool call '%s' was not run: %s. Do not retry it unless the user asks you to.", tool, reason)},
+			},
+			"isError": true,
+		},
+	}
+}
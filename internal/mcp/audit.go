@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/audit"
+	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/metrics"
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+// setAudit opens the audit log configured in cfg, closing the previous one;
+// callers hold s.mu
+func (s *Server) setAudit(cfg config.AuditConfig) {
+	if s.audit != nil {
+		s.audit.Close()
+		s.audit = nil
+	}
+	if cfg.File == "" {
+		return
+	}
+	logger, err := audit.Open(cfg.File, cfg.MaxSizeBytes(), cfg.MaxFiles)
+	if err != nil {
+		slog.Error("Audit log disabled", "error", err)
+		return
+	}
+	s.audit = logger
+}
+
+// auditRecord starts the audit record of a tool call
+func (s *Server) auditRecord(ctx context.Context, sess *session, name string) *audit.Record {
+	rec := &audit.Record{
+		Time:     time.Now().UTC(),
+		Tool:     name,
+		ClientIP: tunnel.ClientIPFromContext(ctx),
+		ExitCode: -1,
+	}
+	if p := principalFromContext(ctx); p != nil {
+		rec.KeyID = p.keyID
+		rec.Subject = p.subject
+	}
+	if sess != nil {
+		sess.mu.Lock()
+		rec.Session = sess.id
+		rec.Client = sess.clientName
+		sess.mu.Unlock()
+	}
+	return rec
+}
+
+// writeAudit finishes an audit record and appends it to the audit log
+func (s *Server) writeAudit(rec *audit.Record, red *redactor, args map[string]interface{}) {
+	s.mu.RLock()
+	logger := s.audit
+	s.mu.RUnlock()
+	if logger == nil {
+		return
+	}
+
+	if rec.DurationMs == 0 {
+		rec.DurationMs = time.Since(rec.Time).Milliseconds()
+	}
+	switch s.GetConfig().Audit.Arguments {
+	case "all":
+		rec.Arguments = args
+	case "none":
+	default:
+		rec.Arguments = red.Arguments(args)
+	}
+	rec.Command = red.String(rec.Command)
+	rec.Error = red.String(rec.Error)
+
+	if err := logger.Write(rec); err != nil {
+		metrics.AuditWriteErrors.Inc()
+		slog.Error("Audit log write failed", "error", err)
+	}
+}
@@ -0,0 +1,196 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/keys"
+	"github.com/gantz-ai/gantz-cli/internal/oauth"
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+// SetAuthToken makes every request, local or relayed, require
+// "Authorization: Bearer <token>". An empty token disables the check.
+func (s *Server) SetAuthToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authToken = token
+}
+
+// SetKeyStore lets clients authenticate with API keys from a key store.
+// While the store has usable keys, requests without a valid key or the auth
+// token are rejected.
+func (s *Server) SetKeyStore(store *keys.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyStore = store
+}
+
+// principal is who a request was authenticated as, when that limits the
+// tools it may use
+type principal struct {
+	name    string // e.g. `API key "ci"`
+	keyID   string // Set for API keys
+	subject string // Set for OAuth tokens
+	allows  func(tool string, tags []string) bool
+}
+
+type principalKey struct{}
+
+// principalFromContext returns who a request was made by, or nil if it
+// needed no credentials or used the auth token
+func principalFromContext(ctx context.Context) *principal {
+	p, _ := ctx.Value(principalKey{}).(*principal)
+	return p
+}
+
+// toolAllowed reports whether the client of a request may use a tool
+func toolAllowed(ctx context.Context, name string, tags []string) bool {
+	p := principalFromContext(ctx)
+	return p == nil || p.allows(name, tags)
+}
+
+// toolDeniedReason explains why the client of a request can't call a tool
+func toolDeniedReason(ctx context.Context) string {
+	return fmt.Sprintf("%s is not allowed to call this tool", principalFromContext(ctx).name)
+}
+
+// Authenticate checks the Authorization header of a request (implements
+// tunnel.MCPHandler)
+func (s *Server) Authenticate(ctx context.Context, authorization string) (context.Context, error) {
+	s.mu.RLock()
+	expected := s.authToken
+	store := s.keyStore
+	validator := s.oauth
+	s.mu.RUnlock()
+	useKeys := store != nil && store.Active()
+	if expected == "" && !useKeys && validator == nil {
+		return ctx, nil
+	}
+
+	reject := func(code, message string) (context.Context, error) {
+		err := &tunnel.AuthError{
+			Status:  http.StatusUnauthorized,
+			Code:    code,
+			Message: message,
+		}
+		if validator != nil {
+			err.ResourceMetadata = validator.MetadataURL()
+		}
+		return nil, err
+	}
+
+	token, ok := bearerToken(authorization)
+	if !ok {
+		return reject("", "Authentication required: send Authorization: Bearer <token>")
+	}
+	if expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+		return ctx, nil
+	}
+
+	if useKeys {
+		key, err := store.Verify(token)
+		if err == nil {
+			name := key.Label
+			if name == "" {
+				name = key.ID
+			}
+			return context.WithValue(ctx, principalKey{}, &principal{
+				name:   fmt.Sprintf("API key %q", name),
+				keyID:  key.ID,
+				allows: key.Allows,
+			}), nil
+		}
+		if err == keys.ErrExpired || err == keys.ErrRevoked {
+			return reject("invalid_token", err.Error())
+		}
+	}
+
+	if validator != nil && oauth.LooksLikeJWT(token) {
+		claims, err := validator.Validate(token)
+		if err == oauth.ErrInsufficientScope {
+			return nil, &tunnel.AuthError{
+				Status:           http.StatusForbidden,
+				Code:             "insufficient_scope",
+				Message:          "Token is missing a required scope",
+				Scope:            strings.Join(validator.RequiredScopes(), " "),
+				ResourceMetadata: validator.MetadataURL(),
+			}
+		}
+		if err != nil {
+			return reject("invalid_token", "Invalid access token: "+err.Error())
+		}
+		name := claims.Subject
+		if name == "" {
+			name = claims.ClientID
+		}
+		return context.WithValue(ctx, principalKey{}, &principal{
+			name:    fmt.Sprintf("token of %q", name),
+			subject: claims.Subject,
+			allows: func(tool string, tags []string) bool {
+				return validator.Allows(claims, tool, tags)
+			},
+		}), nil
+	}
+
+	return reject("invalid_token", "Invalid auth token")
+}
+
+// bearerToken extracts the token from an Authorization header
+func bearerToken(authorization string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// authenticateHTTP authenticates a local HTTP request, writing the error
+// response and returning false if it is rejected
+func (s *Server) authenticateHTTP(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	ctx, err := s.Authenticate(r.Context(), r.Header.Get("Authorization"))
+	if err == nil {
+		return ctx, true
+	}
+
+	slog.Warn("Rejected request", "remote_addr", r.RemoteAddr, "error", err)
+	status := http.StatusUnauthorized
+	if authErr, ok := err.(*tunnel.AuthError); ok {
+		status = authErr.Status
+		w.Header().Set("WWW-Authenticate", authErr.Challenge())
+	}
+	http.Error(w, err.Error(), status)
+	return nil, false
+}
+
+// handleResourceMetadata serves the OAuth protected resource metadata, which
+// tells clients where to get access tokens
+func (s *Server) handleResourceMetadata(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	validator := s.oauth
+	s.mu.RUnlock()
+	if validator == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(validator.Metadata())
+}
+
+// setOAuth rebuilds the token validator when the OAuth configuration
+// changes; callers hold s.mu
+func (s *Server) setOAuth(cfg *config.OAuthConfig) {
+	if cfg == nil {
+		s.oauth = nil
+		return
+	}
+	s.oauth = oauth.NewValidator(cfg)
+}
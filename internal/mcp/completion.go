@@ -0,0 +1,271 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+// maxCompletionValues is the most suggestions MCP allows in one response
+const maxCompletionValues = 100
+
+// defaultCompletionCache is how long command and glob candidates are reused
+const defaultCompletionCache = 10 * time.Second
+
+type completeParams struct {
+	Ref struct {
+		Type string `json:"type"` // "ref/prompt", "ref/resource" or "ref/tool"
+		Name string `json:"name"`
+		URI  string `json:"uri"`
+	} `json:"ref"`
+	Argument struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"argument"`
+	Context struct {
+		Arguments map[string]string `json:"arguments"`
+	} `json:"context"`
+}
+
+func (s *Server) handleComplete(ctx context.Context, sess *session, req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+	var params completeParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return errorResponse(req, -32602, "Invalid params"), nil
+	}
+
+	cfg := s.GetConfig()
+	var completion *config.Completion
+	switch params.Ref.Type {
+	case "ref/prompt":
+		prompt := cfg.GetPrompt(params.Ref.Name)
+		if prompt == nil {
+			return errorResponse(req, -32602, fmt.Sprintf("Prompt not found: %s", params.Ref.Name)), nil
+		}
+		for _, arg := range prompt.Arguments {
+			if arg.Name == params.Argument.Name {
+				completion = arg.Completion
+			}
+		}
+	case "ref/tool":
+		// Not part of the MCP spec, but lets clients complete tool arguments too
+		// Tools the client may not call don't exist for it here either
+		tool := cfg.GetTool(params.Ref.Name)
+		if tool == nil || !toolAllowed(ctx, tool.Name, tool.Tags) {
+			return errorResponse(req, -32602, fmt.Sprintf("Tool not found: %s", params.Ref.Name)), nil
+		}
+		for _, param := range tool.Parameters {
+			if param.Name == params.Argument.Name {
+				completion = param.Completion
+			}
+		}
+	case "ref/resource":
+		// Resource template parameters have no completion sources
+	default:
+		return errorResponse(req, -32602, fmt.Sprintf("Unknown reference type: %s", params.Ref.Type)), nil
+	}
+
+	var values []string
+	if completion != nil {
+		candidates, err := s.completionCandidates(ctx, sess, req, completion, params.Argument.Value, params.Context.Arguments)
+		if limited := limitedResponse(err); limited != nil {
+			return limited, nil
+		}
+		if err != nil {
+			return errorResponse(req, -32603, fmt.Sprintf("Completion failed: %v", err)), nil
+		}
+		values = filterCandidates(candidates, params.Argument.Value)
+	}
+
+	total := len(values)
+	if total > maxCompletionValues {
+		values = values[:maxCompletionValues]
+	}
+	if values == nil {
+		values = []string{}
+	}
+
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"completion": map[string]interface{}{
+				"values":  values,
+				"total":   total,
+				"hasMore": total > maxCompletionValues,
+			},
+		},
+	}, nil
+}
+
+// completionCandidates returns the unfiltered candidates of a completion
+// source, from the cache when possible
+func (s *Server) completionCandidates(ctx context.Context, sess *session, req *tunnel.MCPRequest, c *config.Completion, value string, others map[string]string) ([]string, error) {
+	if len(c.Values) > 0 {
+		return c.Values, nil
+	}
+
+	// Commands may use {{value}} and the other arguments, so the cache key is
+	// the command after substitution
+	args := make(map[string]string, len(others)+1)
+	for k, v := range others {
+		if argNamePattern.MatchString(k) {
+			args[k] = v
+		}
+	}
+	args["value"] = value
+
+	key := "glob:" + c.Glob
+	if c.Command != "" {
+		key = "command:" + expandPlaceholders(c.Command, args)
+	}
+	if values, ok := s.completions.get(key); ok {
+		return values, nil
+	}
+
+	var values []string
+	if c.Command != "" {
+		out, err := s.runCompletionCommand(ctx, sess, req, c.Command, args)
+		if err != nil {
+			return nil, err
+		}
+		values = splitCandidates(out)
+	} else {
+		matches, err := filepath.Glob(c.Glob)
+		if err != nil {
+			return nil, err
+		}
+		values = matches
+	}
+
+	ttl := defaultCompletionCache
+	if c.Cache != "" {
+		ttl, _ = time.ParseDuration(c.Cache)
+	}
+	s.completions.put(key, values, ttl)
+	return values, nil
+}
+
+// argNamePattern matches the argument names a completion command gets;
+// others couldn't be passed as environment variables
+var argNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envReferences replaces {{name}} placeholders with references to the
+// GANTZ_ARG_ variables holding the values, so text typed by the client is
+// never parsed as shell code
+func envReferences(command string, args map[string]string) string {
+	for k := range args {
+		name := "GANTZ_ARG_" + strings.ToUpper(k)
+		ref := `"$` + name + `"`
+		if runtime.GOOS == "windows" {
+			ref = "%" + name + "%"
+		}
+		command = strings.ReplaceAll(command, "{{"+k+"}}", ref)
+	}
+	return command
+}
+
+// runCompletionCommand runs a completion command through the script
+// executor, with the arguments in the environment. Like tool calls, it
+// counts against the rate limit and max_concurrency.
+func (s *Server) runCompletionCommand(ctx context.Context, sess *session, req *tunnel.MCPRequest, command string, args map[string]string) (string, error) {
+	release, err := s.limitCommand(ctx, sess, req, "completion")
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	toolArgs := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		toolArgs[k] = v
+	}
+	tool := &config.Tool{
+		Name:   "completion",
+		Script: config.ScriptConfig{Shell: envReferences(command, args), Timeout: "5s"},
+	}
+	result := s.executor.Execute(ctx, tool, toolArgs)
+	if result.ExitCode != 0 {
+		if result.Output != "" {
+			return "", fmt.Errorf("%s", result.Output)
+		}
+		return "", result.Error
+	}
+	return result.Output, nil
+}
+
+// splitCandidates turns command output into unique, non-empty lines
+func splitCandidates(out string) []string {
+	seen := map[string]bool{}
+	var values []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		values = append(values, line)
+	}
+	return values
+}
+
+// filterCandidates keeps candidates containing the typed value, case
+// insensitively, with prefix matches first
+func filterCandidates(candidates []string, value string) []string {
+	value = strings.ToLower(value)
+	var prefix, contains []string
+	for _, c := range candidates {
+		lower := strings.ToLower(c)
+		switch {
+		case strings.HasPrefix(lower, value):
+			prefix = append(prefix, c)
+		case strings.Contains(lower, value):
+			contains = append(contains, c)
+		}
+	}
+	return append(prefix, contains...)
+}
+
+// completionCache keeps completion candidates for a short time so typing in a
+// client doesn't run a command per keystroke
+type completionCache struct {
+	mu      sync.Mutex
+	entries map[string]completionEntry
+}
+
+type completionEntry struct {
+	values  []string
+	expires time.Time
+}
+
+func (c *completionCache) get(key string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.values, true
+}
+
+func (c *completionCache) put(key string, values []string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]completionEntry)
+	}
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = completionEntry{values: values, expires: now.Add(ttl)}
+}
@@ -0,0 +1,222 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+// Environment variables that let a running script reach the helper socket
+const (
+	HelperSocketEnv = "GANTZ_SOCKET"
+	HelperTokenEnv  = "GANTZ_CALL_TOKEN"
+)
+
+// helperMethods are the client requests scripts may make, with the client
+// capability each one needs
+var helperMethods = map[string]string{
+	"elicitation/create":     "elicitation",
+	"sampling/createMessage": "sampling",
+	"roots/list":             "roots",
+}
+
+// helperSocket is a local socket through which scripts send requests to the
+// client whose tool call started them. Each call gets its own token.
+type helperSocket struct {
+	path     string
+	listener net.Listener
+	mu       sync.Mutex
+	calls    map[string]helperCall
+}
+
+// helperCall is the client of a running tool call
+type helperCall struct {
+	peer  tunnel.Peer
+	sess  *session
+	owner string // requestOwner of the client
+}
+
+type helperRequest struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type helperError struct {
+	Error string `json:"error"`
+}
+
+// helperEnv returns the environment a script needs to use the helper socket
+// during a tool call, and a function to call when the tool finishes
+func (s *Server) helperEnv(ctx context.Context, sess *session) ([]string, func()) {
+	s.helperOnce.Do(func() {
+		helper, err := s.startHelper()
+		if err != nil {
+			slog.Warn("Helper socket unavailable, scripts cannot use 'gantz ask'", "error", err)
+			return
+		}
+		s.helper = helper
+	})
+	if s.helper == nil {
+		return nil, func() {}
+	}
+
+	token := randomToken()
+	s.helper.mu.Lock()
+	s.helper.calls[token] = helperCall{peer: tunnel.PeerFromContext(ctx), sess: sess, owner: requestOwner(ctx, sess)}
+	s.helper.mu.Unlock()
+
+	env := []string{
+		HelperSocketEnv + "=" + s.helper.path,
+		HelperTokenEnv + "=" + token,
+	}
+	return env, func() {
+		s.helper.mu.Lock()
+		delete(s.helper.calls, token)
+		s.helper.mu.Unlock()
+	}
+}
+
+func (s *Server) startHelper() (*helperSocket, error) {
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("gantz-%d-%s.sock", os.Getpid(), randomToken()[:8]))
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	os.Chmod(path, 0600)
+
+	h := &helperSocket{
+		path:     path,
+		listener: listener,
+		calls:    make(map[string]helperCall),
+	}
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleHelperRequest(h, w, r)
+	}))
+	return h, nil
+}
+
+func (h *helperSocket) close() {
+	h.listener.Close()
+	os.Remove(h.path)
+}
+
+func (s *Server) handleHelperRequest(h *helperSocket, w http.ResponseWriter, r *http.Request) {
+	writeError := func(status int, msg string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(helperError{Error: msg})
+	}
+
+	if r.Method != http.MethodPost || r.URL.Path != "/request" {
+		writeError(http.StatusNotFound, "not found")
+		return
+	}
+
+	h.mu.Lock()
+	call, ok := h.calls[r.Header.Get("X-Gantz-Call-Token")]
+	h.mu.Unlock()
+	if !ok {
+		writeError(http.StatusForbidden, "unknown or finished tool call")
+		return
+	}
+	if call.peer == nil {
+		writeError(http.StatusConflict, "the client of this tool call cannot receive requests")
+		return
+	}
+
+	var req helperRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	capability, ok := helperMethods[req.Method]
+	if !ok {
+		writeError(http.StatusBadRequest, fmt.Sprintf("method not allowed: %s", req.Method))
+		return
+	}
+	if call.sess != nil && !call.sess.isReady() {
+		writeError(http.StatusConflict, "the client has not finished initializing")
+		return
+	}
+	if call.sess != nil && !call.sess.hasCapability(capability) {
+		writeError(http.StatusConflict, fmt.Sprintf("the client does not support %s", capability))
+		return
+	}
+
+	result, err := s.requestClient(r.Context(), call.peer, call.owner, req.Method, req.Params)
+	if err != nil {
+		writeError(http.StatusBadGateway, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(result)
+}
+
+// HelperRequest sends a request to the client of the tool call the current
+// process was started by, through the helper socket
+func HelperRequest(method string, params interface{}) (json.RawMessage, error) {
+	path, token := os.Getenv(HelperSocketEnv), os.Getenv(HelperTokenEnv)
+	if path == "" || token == "" {
+		return nil, fmt.Errorf("not running inside a gantz tool call (%s is not set)", HelperSocketEnv)
+	}
+
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(helperRequest{Method: method, Params: rawParams})
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	req, err := http.NewRequest(http.MethodPost, "http://gantz/request", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gantz-Call-Token", token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("helper socket: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var herr helperError
+		if json.Unmarshal(data, &herr) == nil && herr.Error != "" {
+			return nil, fmt.Errorf("%s", herr.Error)
+		}
+		return nil, fmt.Errorf("helper socket: %s", resp.Status)
+	}
+	return data, nil
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
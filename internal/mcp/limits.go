@@ -0,0 +1,278 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+// defaultQueueTimeout is how long a tool call waits for a free slot unless
+// limits.queue_timeout says otherwise
+const defaultQueueTimeout = 30 * time.Second
+
+// retryableError is the JSON-RPC error code for calls rejected by a limit;
+// the client may try again after data.retryAfterMs
+const retryableError = -32000
+
+// slots is a counting semaphore whose size can change between calls.
+// Waiting calls are served in order.
+type slots struct {
+	mu      sync.Mutex
+	used    int
+	limit   int
+	waiters []chan struct{}
+}
+
+// acquire takes a slot if fewer than limit are in use, or waits for one
+// until ctx is done. A limit of 0 means unlimited.
+func (sl *slots) acquire(ctx context.Context, limit int) error {
+	sl.mu.Lock()
+	sl.limit = limit
+	if limit <= 0 || (sl.used < limit && len(sl.waiters) == 0) {
+		sl.used++
+		sl.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	sl.waiters = append(sl.waiters, ch)
+	sl.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		sl.mu.Lock()
+		defer sl.mu.Unlock()
+		for i, w := range sl.waiters {
+			if w == ch {
+				sl.waiters = append(sl.waiters[:i], sl.waiters[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// The slot was handed over just as the wait ended; pass it on
+		sl.releaseLocked()
+		return ctx.Err()
+	}
+}
+
+func (sl *slots) release() {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	sl.releaseLocked()
+}
+
+func (sl *slots) releaseLocked() {
+	sl.used--
+	for len(sl.waiters) > 0 && (sl.limit <= 0 || sl.used < sl.limit) {
+		close(sl.waiters[0])
+		sl.waiters = sl.waiters[1:]
+		sl.used++
+	}
+}
+
+// running returns how many slots are in use
+func (sl *slots) running() int {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	return sl.used
+}
+
+// limiter enforces limits.max_concurrency, per-tool max_concurrency and
+// limits.rate_limit
+type limiter struct {
+	global slots
+
+	mu      sync.Mutex
+	tools   map[string]*slots
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// bucket is the token bucket of one client
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (l *limiter) toolSlots(name string) *slots {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tools == nil {
+		l.tools = make(map[string]*slots)
+	}
+	sl, ok := l.tools[name]
+	if !ok {
+		sl = &slots{}
+		l.tools[name] = sl
+	}
+	return sl
+}
+
+// allow takes a token from the client's bucket. If there is none, it
+// returns how long until there will be.
+func (l *limiter) allow(client string, rl *config.RateLimit) (bool, time.Duration) {
+	per, _ := time.ParseDuration(rl.Per)
+	rate := float64(rl.Requests) / per.Seconds() // Tokens per second
+	burst := float64(rl.Burst)
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.buckets == nil {
+		l.buckets = make(map[string]*bucket)
+	}
+
+	// Forget clients whose buckets have refilled
+	if now.Sub(l.swept) > per {
+		for key, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*rate >= burst {
+				delete(l.buckets, key)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait
+}
+
+// clientKey identifies the client of a request for rate limiting: its API
+// key or token if it authenticated with one, otherwise its address
+func clientKey(ctx context.Context) string {
+	if p := principalFromContext(ctx); p != nil {
+		return p.name
+	}
+	if ip := tunnel.ClientIPFromContext(ctx); ip != "" {
+		return ip
+	}
+	return "unknown client"
+}
+
+// checkRateLimit returns a retryable error response if the client of a
+// request has made too many tool calls
+func (s *Server) checkRateLimit(ctx context.Context, sess *session, req *tunnel.MCPRequest, tool string) *tunnel.MCPResponse {
+	rl := s.GetConfig().Limits.RateLimit
+	if rl == nil {
+		return nil
+	}
+	client := clientKey(ctx)
+	ok, wait := s.limits.allow(client, rl)
+	if ok {
+		return nil
+	}
+
+	wait = wait.Round(time.Millisecond)
+	slog.Warn("Rate limited", "client", client, "tool", tool, "retry_in", wait)
+	s.logSession(sess, "warning", "tool/"+tool, map[string]interface{}{
+		"event":        "rate_limited",
+		"retryAfterMs": wait.Milliseconds(),
+	})
+	return retryableResponse(req, fmt.Sprintf("Rate limit exceeded for %s; retry in %v", client, wait), wait)
+}
+
+// acquireSlots waits for a free global slot and, if the tool has a
+// max_concurrency, a free slot of the tool. It returns a function that
+// frees them, or a retryable error response if the wait timed out.
+func (s *Server) acquireSlots(ctx context.Context, sess *session, req *tunnel.MCPRequest, tool string, toolLimit int) (func(), *tunnel.MCPResponse) {
+	limits := s.GetConfig().Limits
+	if limits.MaxConcurrency <= 0 && toolLimit <= 0 {
+		return func() {}, nil
+	}
+
+	timeout := defaultQueueTimeout
+	if d, err := time.ParseDuration(limits.QueueTimeout); err == nil {
+		timeout = d
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	toolSlots := s.limits.toolSlots(tool)
+	if err := toolSlots.acquire(waitCtx, toolLimit); err != nil {
+		return nil, s.queueTimeout(sess, req, tool, fmt.Sprintf("%d calls of %s are already running", toolSlots.running(), tool), timeout)
+	}
+	if err := s.limits.global.acquire(waitCtx, limits.MaxConcurrency); err != nil {
+		toolSlots.release()
+		return nil, s.queueTimeout(sess, req, tool, fmt.Sprintf("%d tool calls are already running", s.limits.global.running()), timeout)
+	}
+	return func() {
+		s.limits.global.release()
+		toolSlots.release()
+	}, nil
+}
+
+// limitedError carries the retryable response of a command turned away by a
+// limit up to the request handler
+type limitedError struct {
+	resp *tunnel.MCPResponse
+}
+
+func (e *limitedError) Error() string {
+	return e.resp.Error.Message
+}
+
+// limitCommand applies limits.rate_limit and limits.max_concurrency to a
+// command run outside tools/call, such as a completion command or a command
+// resource. It returns a function that frees the slot.
+func (s *Server) limitCommand(ctx context.Context, sess *session, req *tunnel.MCPRequest, name string) (func(), error) {
+	if limited := s.checkRateLimit(ctx, sess, req, name); limited != nil {
+		return nil, &limitedError{resp: limited}
+	}
+	release, busy := s.acquireSlots(ctx, sess, req, name, 0)
+	if busy != nil {
+		return nil, &limitedError{resp: busy}
+	}
+	return release, nil
+}
+
+// limitedResponse returns the retryable response of an error from
+// limitCommand, or nil for other errors
+func limitedResponse(err error) *tunnel.MCPResponse {
+	var limited *limitedError
+	if errors.As(err, &limited) {
+		return limited.resp
+	}
+	return nil
+}
+
+func (s *Server) queueTimeout(sess *session, req *tunnel.MCPRequest, tool, reason string, waited time.Duration) *tunnel.MCPResponse {
+	slog.Warn("Queue timeout", "tool", tool, "reason", reason)
+	s.logSession(sess, "warning", "tool/"+tool, map[string]interface{}{
+		"event":  "queue_timeout",
+		"reason": reason,
+	})
+	return retryableResponse(req, fmt.Sprintf("Server busy: %s; waited %v for a free slot", reason, waited), time.Second)
+}
+
+// retryableResponse is a JSON-RPC error telling the client to try again later
+func retryableResponse(req *tunnel.MCPRequest, message string, retryAfter time.Duration) *tunnel.MCPResponse {
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Error: &tunnel.MCPError{
+			Code:    retryableError,
+			Message: message,
+			Data: map[string]interface{}{
+				"retryable":    true,
+				"retryAfterMs": retryAfter.Milliseconds(),
+			},
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+// logLevels are the syslog severities defined by MCP, from least to most severe
+var logLevels = []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// defaultLogLevel is used until a client sends logging/setLevel
+const defaultLogLevel = "info"
+
+func logSeverity(level string) int {
+	for i, l := range logLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+type setLevelParams struct {
+	Level string `json:"level"`
+}
+
+func (s *Server) handleLoggingSetLevel(sess *session, req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+	var params setLevelParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return errorResponse(req, -32602, "Invalid params"), nil
+	}
+	severity := logSeverity(params.Level)
+	if severity < 0 {
+		return errorResponse(req, -32602, fmt.Sprintf("Invalid log level: %s", params.Level)), nil
+	}
+
+	if sess != nil {
+		sess.mu.Lock()
+		sess.logLevel = severity
+		sess.mu.Unlock()
+	}
+
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  map[string]interface{}{},
+	}, nil
+}
+
+// Log sends a notifications/message to every client that asked for messages
+// of this level
+func (s *Server) Log(level, logger string, data interface{}) {
+	for _, sess := range s.sessions.all() {
+		s.logSession(sess, level, logger, data)
+	}
+}
+
+// logSession sends a notifications/message to one client if it asked for
+// messages of this level
+func (s *Server) logSession(sess *session, level, logger string, data interface{}) {
+	if sess == nil {
+		return
+	}
+	sess.mu.Lock()
+	min := sess.logLevel
+	sess.mu.Unlock()
+	if logSeverity(level) < min {
+		return
+	}
+
+	sess.notify(notification("notifications/message", map[string]interface{}{
+		"level":  level,
+		"logger": logger,
+		"data":   data,
+	}))
+}
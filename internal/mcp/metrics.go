@@ -0,0 +1,20 @@
+package mcp
+
+import (
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/audit"
+	"github.com/gantz-ai/gantz-cli/internal/metrics"
+)
+
+// observeToolCall counts a finished tool call in the metrics
+func observeToolCall(rec *audit.Record) {
+	tool := rec.Tool
+	if rec.Status == "not_found" {
+		tool = "" // Names of unknown tools come from clients; don't make a series for each
+	}
+	metrics.ToolCalls.With(tool, rec.Status).Inc()
+	if rec.Status == "ok" || rec.Status == "failed" {
+		metrics.ToolDuration.With(tool).Observe(time.Since(rec.Time).Seconds())
+	}
+}
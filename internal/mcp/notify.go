@@ -0,0 +1,75 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+func notification(method string, params interface{}) *tunnel.MCPNotification {
+	return &tunnel.MCPNotification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	}
+}
+
+// broadcast sends a notification to every connected client
+func (s *Server) broadcast(method string, params interface{}) {
+	n := notification(method, params)
+	for _, sess := range s.sessions.all() {
+		sess.notify(n)
+	}
+}
+
+// notifySubscribers tells the sessions subscribed to a resource that it changed
+func (s *Server) notifySubscribers(uri string) {
+	n := notification("notifications/resources/updated", map[string]interface{}{"uri": uri})
+	for _, sess := range s.sessions.all() {
+		sess.mu.Lock()
+		subscribed := sess.subscriptions[uri]
+		sess.mu.Unlock()
+		if subscribed {
+			sess.notify(n)
+		}
+	}
+}
+
+// sseStream writes notifications and server-initiated requests to an open
+// SSE response
+type sseStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// Notify delivers a notification (implements tunnel.Notifier)
+func (st *sseStream) Notify(n *tunnel.MCPNotification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return st.send("message", string(data))
+}
+
+// SendRequest delivers a server-initiated request (implements tunnel.Peer)
+func (st *sseStream) SendRequest(req *tunnel.MCPRequest) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return st.send("message", string(data))
+}
+
+func (st *sseStream) send(event, data string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, err := fmt.Fprintf(st.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	st.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+func (s *Server) handlePromptsList(req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+	cfg := s.GetConfig()
+	prompts := make([]map[string]interface{}, 0, len(cfg.Prompts))
+
+	for _, prompt := range cfg.Prompts {
+		args := make([]map[string]interface{}, 0, len(prompt.Arguments))
+		for _, arg := range prompt.Arguments {
+			entry := map[string]interface{}{
+				"name":     arg.Name,
+				"required": arg.Required,
+			}
+			if arg.Description != "" {
+				entry["description"] = arg.Description
+			}
+			args = append(args, entry)
+		}
+
+		entry := map[string]interface{}{
+			"name":      prompt.Name,
+			"arguments": args,
+		}
+		if prompt.Description != "" {
+			entry["description"] = prompt.Description
+		}
+		prompts = append(prompts, entry)
+	}
+
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"prompts": prompts,
+		},
+	}, nil
+}
+
+type promptGetParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments"`
+}
+
+func (s *Server) handlePromptsGet(ctx context.Context, sess *session, req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+	var params promptGetParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return errorResponse(req, -32602, "Invalid params"), nil
+	}
+
+	cfg := s.GetConfig()
+	prompt := cfg.GetPrompt(params.Name)
+	if prompt == nil {
+		return errorResponse(req, -32602, fmt.Sprintf("Prompt not found: %s", params.Name)), nil
+	}
+
+	for _, arg := range prompt.Arguments {
+		if arg.Required && params.Arguments[arg.Name] == "" {
+			return errorResponse(req, -32602, fmt.Sprintf("Missing required argument: %s", arg.Name)), nil
+		}
+	}
+
+	// Unset optional arguments expand to empty strings
+	values := make(map[string]string, len(prompt.Arguments))
+	for _, arg := range prompt.Arguments {
+		values[arg.Name] = params.Arguments[arg.Name]
+	}
+
+	source := prompt.Messages
+	if prompt.Template != "" {
+		source = []config.PromptMessage{{Role: "user", Text: prompt.Template}}
+	}
+
+	messages := make([]map[string]interface{}, 0, len(source))
+	for _, msg := range source {
+		var content map[string]interface{}
+		if msg.Resource != "" {
+			uri := expandPlaceholders(msg.Resource, values)
+			match := resolveResource(cfg, uri)
+			if match == nil {
+				return errorResponse(req, -32602, fmt.Sprintf("Prompt '%s' embeds unknown resource: %s", prompt.Name, uri)), nil
+			}
+			resource, err := s.readResource(ctx, sess, req, match)
+			if limited := limitedResponse(err); limited != nil {
+				return limited, nil
+			}
+			if err != nil {
+				return errorResponse(req, -32603, fmt.Sprintf("Failed to read resource %s: %v", uri, err)), nil
+			}
+			content = map[string]interface{}{
+				"type":     "resource",
+				"resource": resource,
+			}
+		} else {
+			content = map[string]interface{}{
+				"type": "text",
+				"text": strings.TrimSpace(expandPlaceholders(msg.Text, values)),
+			}
+		}
+		messages = append(messages, map[string]interface{}{
+			"role":    msg.Role,
+			"content": content,
+		})
+	}
+
+	result := map[string]interface{}{
+		"messages": messages,
+	}
+	if prompt.Description != "" {
+		result["description"] = prompt.Description
+	}
+
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}, nil
+}
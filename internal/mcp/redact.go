@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+)
+
+// redacted replaces secret values in logs, audit records and output
+const redacted = "[REDACTED]"
+
+// redactPatterns caches compiled redact: patterns; they were checked when
+// the config was loaded
+var redactPatterns sync.Map
+
+// redactor masks the values of a call's secret parameters, the environment
+// variables in its tool's config and the matches of its redact: patterns
+type redactor struct {
+	secrets  map[string]bool // Names of secret parameters
+	values   []string        // Their values, longest first
+	patterns []*regexp.Regexp
+}
+
+// newRedactor returns the redactor for a call of tool, which may be nil
+func newRedactor(tool *config.Tool, args map[string]interface{}) *redactor {
+	r := &redactor{secrets: map[string]bool{}}
+	if tool == nil {
+		return r
+	}
+	// Environment values expanded into the tool's config often hold keys
+	values := append([]string(nil), tool.EnvValues...)
+	for _, param := range tool.Parameters {
+		if !param.Secret {
+			continue
+		}
+		r.secrets[param.Name] = true
+		if v, ok := args[param.Name]; ok && v != nil {
+			values = append(values, fmt.Sprintf("%v", v))
+		}
+	}
+	r.addValues(values)
+	for _, pattern := range tool.Redact {
+		re, ok := redactPatterns.Load(pattern)
+		if !ok {
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				continue
+			}
+			re, _ = redactPatterns.LoadOrStore(pattern, compiled)
+		}
+		r.patterns = append(r.patterns, re.(*regexp.Regexp))
+	}
+	return r
+}
+
+// addValues adds values to mask, such as resolved secret references
+func (r *redactor) addValues(values []string) {
+	for _, v := range values {
+		if v != "" {
+			r.values = append(r.values, v)
+		}
+	}
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+}
+
+// String masks secret values and pattern matches in s
+func (r *redactor) String(s string) string {
+	for _, v := range r.values {
+		s = strings.ReplaceAll(s, v, redacted)
+	}
+	for _, re := range r.patterns {
+		s = maskPattern(re, s)
+	}
+	return s
+}
+
+// Arguments returns a copy of args with secret parameters replaced and
+// pattern matches masked in the other values
+func (r *redactor) Arguments(args map[string]interface{}) map[string]interface{} {
+	if len(args) == 0 || (len(r.secrets) == 0 && len(r.patterns) == 0) {
+		return args
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if r.secrets[k] {
+			out[k] = redacted
+		} else {
+			out[k] = r.value(v)
+		}
+	}
+	return out
+}
+
+func (r *redactor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = r.value(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = r.value(item)
+		}
+		return out
+	}
+	return v
+}
+
+// maskPattern masks the matches of re in s. If re has groups, only what
+// they match is masked, so "token=(\S+)" keeps the "token=".
+func maskPattern(re *regexp.Regexp, s string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllLiteralString(s, redacted)
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		for g := 1; g <= re.NumSubexp(); g++ {
+			start, end := m[2*g], m[2*g+1]
+			if start < last || start == end { // Unmatched, empty or nested group
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(redacted)
+			last = end
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+// clientRequests tracks requests sent to clients until they answer
+type clientRequests struct {
+	mu      sync.Mutex
+	pending map[string]pendingRequest
+}
+
+// pendingRequest waits for the answer to a request sent to a client
+type pendingRequest struct {
+	owner string // requestOwner of the client it was sent to
+	ch    chan *tunnel.MCPResponse
+}
+
+// requestOwner identifies the client a message came from by its session
+// and credentials, so one client can't answer requests sent to another
+func requestOwner(ctx context.Context, sess *session) string {
+	id := ""
+	if sess != nil {
+		id = sess.id
+	} else if ref := tunnel.SessionFromContext(ctx); ref != nil {
+		id = ref.ID
+	}
+	if p := principalFromContext(ctx); p != nil {
+		return id + "\x00" + p.keyID + "\x00" + p.subject + "\x00" + p.name
+	}
+	return id
+}
+
+// requestClient sends a request to the client behind peer, whose
+// requestOwner is owner, and waits for its answer or for ctx to be done
+func (s *Server) requestClient(ctx context.Context, peer tunnel.Peer, owner, method string, params interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Random IDs, so a client can't guess the requests sent to others
+	id := "gantz-" + randomToken()
+	ch := make(chan *tunnel.MCPResponse, 1)
+	s.clientRequests.mu.Lock()
+	if s.clientRequests.pending == nil {
+		s.clientRequests.pending = make(map[string]pendingRequest)
+	}
+	s.clientRequests.pending[id] = pendingRequest{owner: owner, ch: ch}
+	s.clientRequests.mu.Unlock()
+
+	defer func() {
+		s.clientRequests.mu.Lock()
+		delete(s.clientRequests.pending, id)
+		s.clientRequests.mu.Unlock()
+	}()
+
+	req := &tunnel.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  data,
+	}
+	if err := peer.SendRequest(req); err != nil {
+		return nil, fmt.Errorf("send %s: %w", method, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, fmt.Errorf("client rejected %s: %s", method, resp.Error.Message)
+		}
+		return json.Marshal(resp.Result)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// HandleResponse delivers a client's answer to a request sent by the server
+// (implements tunnel.MCPHandler). Answers from a client other than the one
+// the request was sent to are dropped.
+func (s *Server) HandleResponse(ctx context.Context, resp *tunnel.MCPResponse) {
+	id, ok := resp.ID.(string)
+	if !ok {
+		return
+	}
+
+	s.clientRequests.mu.Lock()
+	pending, ok := s.clientRequests.pending[id]
+	s.clientRequests.mu.Unlock()
+	if !ok {
+		return
+	}
+	if requestOwner(ctx, nil) != pending.owner {
+		slog.Warn("Dropped response from another client", "id", id)
+		return
+	}
+	select {
+	case pending.ch <- resp:
+	default: // Duplicate answer
+	}
+}
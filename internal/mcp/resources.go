@@ -0,0 +1,525 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+	"github.com/gantz-ai/gantz-cli/internal/executor"
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+// resourceMatch is a concrete resource resolved from a URI
+type resourceMatch struct {
+	res    *config.Resource
+	uri    string
+	path   string            // Local file backing the resource, if any
+	params map[string]string // Values of URI template parameters
+}
+
+func (s *Server) handleResourcesList(req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+	cfg := s.GetConfig()
+	resources := []map[string]interface{}{}
+
+	for i := range cfg.Resources {
+		res := &cfg.Resources[i]
+		switch {
+		case res.IsTemplate():
+			continue
+		case res.Glob != "":
+			matches, _ := filepath.Glob(res.Glob)
+			for _, match := range matches {
+				if info, err := os.Stat(match); err != nil || info.IsDir() {
+					continue
+				}
+				entry := map[string]interface{}{
+					"uri":  fileURI(match),
+					"name": filepath.Base(match),
+				}
+				if res.Description != "" {
+					entry["description"] = res.Description
+				}
+				if mimeType := resourceMimeType(res, match, nil); mimeType != "" {
+					entry["mimeType"] = mimeType
+				}
+				resources = append(resources, entry)
+			}
+		default:
+			entry := map[string]interface{}{
+				"uri":  res.URI,
+				"name": res.Name,
+			}
+			if res.Description != "" {
+				entry["description"] = res.Description
+			}
+			if mimeType := resourceMimeType(res, res.File, nil); mimeType != "" {
+				entry["mimeType"] = mimeType
+			}
+			resources = append(resources, entry)
+		}
+	}
+
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"resources": resources,
+		},
+	}, nil
+}
+
+func (s *Server) handleResourcesTemplatesList(req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+	cfg := s.GetConfig()
+	templates := []map[string]interface{}{}
+
+	for _, res := range cfg.Resources {
+		if !res.IsTemplate() {
+			continue
+		}
+		entry := map[string]interface{}{
+			"uriTemplate": res.URI,
+			"name":        res.Name,
+		}
+		if res.Description != "" {
+			entry["description"] = res.Description
+		}
+		if res.MimeType != "" {
+			entry["mimeType"] = res.MimeType
+		}
+		templates = append(templates, entry)
+	}
+
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"resourceTemplates": templates,
+		},
+	}, nil
+}
+
+type resourceURIParams struct {
+	URI string `json:"uri"`
+}
+
+func (s *Server) handleResourcesRead(ctx context.Context, sess *session, req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+	var params resourceURIParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return errorResponse(req, -32602, "Invalid params"), nil
+	}
+
+	match := resolveResource(s.GetConfig(), params.URI)
+	if match == nil {
+		return errorResponse(req, -32002, fmt.Sprintf("Resource not found: %s", params.URI)), nil
+	}
+
+	contents, err := s.readResource(ctx, sess, req, match)
+	if limited := limitedResponse(err); limited != nil {
+		return limited, nil
+	}
+	if err != nil {
+		return errorResponse(req, -32603, fmt.Sprintf("Failed to read resource: %v", err)), nil
+	}
+
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"contents": []map[string]interface{}{contents},
+		},
+	}, nil
+}
+
+func (s *Server) handleResourcesSubscribe(sess *session, req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+	var params resourceURIParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return errorResponse(req, -32602, "Invalid params"), nil
+	}
+
+	match := resolveResource(s.GetConfig(), params.URI)
+	if match == nil {
+		return errorResponse(req, -32002, fmt.Sprintf("Resource not found: %s", params.URI)), nil
+	}
+
+	// Only file-backed resources produce update notifications
+	if match.path != "" && sess != nil {
+		sess.mu.Lock()
+		sess.subscriptions[params.URI] = true
+		sess.mu.Unlock()
+		s.resourceWatcher.subscribe(params.URI, match.path)
+	}
+
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  map[string]interface{}{},
+	}, nil
+}
+
+func (s *Server) handleResourcesUnsubscribe(sess *session, req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+	var params resourceURIParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return errorResponse(req, -32602, "Invalid params"), nil
+	}
+
+	if sess != nil {
+		sess.mu.Lock()
+		delete(sess.subscriptions, params.URI)
+		sess.mu.Unlock()
+		s.releaseSubscription(params.URI)
+	}
+
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  map[string]interface{}{},
+	}, nil
+}
+
+// resolveResource finds the configured resource serving a URI
+func resolveResource(cfg *config.Config, uri string) *resourceMatch {
+	// Exact URIs take precedence over globs and templates
+	for i := range cfg.Resources {
+		res := &cfg.Resources[i]
+		if !res.IsTemplate() && res.Glob == "" && res.URI == uri {
+			return &resourceMatch{res: res, uri: uri, path: res.File}
+		}
+	}
+
+	for i := range cfg.Resources {
+		res := &cfg.Resources[i]
+		if res.Glob == "" || !strings.HasPrefix(uri, "file://") {
+			continue
+		}
+		path := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(uri, "file://")))
+		if ok, _ := filepath.Match(res.Glob, path); !ok {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return &resourceMatch{res: res, uri: uri, path: path}
+		}
+	}
+
+	for i := range cfg.Resources {
+		res := &cfg.Resources[i]
+		if !res.IsTemplate() {
+			continue
+		}
+		params, ok := matchURITemplate(res.URI, uri)
+		if !ok {
+			continue
+		}
+		match := &resourceMatch{res: res, uri: uri, params: params}
+		if res.File != "" {
+			match.path = expandPlaceholders(res.File, params)
+		}
+		return match
+	}
+
+	return nil
+}
+
+// readResource loads a resource's content as an MCP text or blob entry.
+// Commands count against the rate limit and max_concurrency, like tool calls.
+func (s *Server) readResource(ctx context.Context, sess *session, req *tunnel.MCPRequest, match *resourceMatch) (map[string]interface{}, error) {
+	res := match.res
+	var data []byte
+
+	switch {
+	case match.path != "":
+		var err error
+		data, err = os.ReadFile(match.path)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		// Commands and HTTP fetches reuse the tool executors
+		args := make(map[string]interface{}, len(match.params))
+		for k, v := range match.params {
+			args[k] = v
+		}
+		// Scoped keys and tokens run these only if they allow a tool named
+		// resource:<name>, so a key limited to some tools can't run commands
+		tool := &config.Tool{Name: "resource:" + res.Name}
+		if !toolAllowed(ctx, tool.Name, nil) {
+			return nil, fmt.Errorf("%s is not allowed to read %s", principalFromContext(ctx).name, match.uri)
+		}
+		var result *executor.Result
+		if res.Command != "" {
+			release, err := s.limitCommand(ctx, sess, req, tool.Name)
+			if err != nil {
+				return nil, err
+			}
+			tool.Script = config.ScriptConfig{Shell: res.Command, Timeout: res.Timeout}
+			result = s.executor.Execute(ctx, tool, args)
+			release()
+		} else {
+			tool.HTTP = config.HTTPConfig{Method: http.MethodGet, URL: res.URL, Headers: res.Headers, Timeout: res.Timeout}
+			result = s.httpExecutor.Execute(ctx, tool, args)
+		}
+		if result.ExitCode != 0 {
+			if result.Output != "" {
+				return nil, fmt.Errorf("%s", result.Output)
+			}
+			return nil, result.Error
+		}
+		data = []byte(result.Output)
+	}
+
+	contents := map[string]interface{}{
+		"uri": match.uri,
+	}
+	mimeType := resourceMimeType(res, match.path, data)
+	if mimeType != "" {
+		contents["mimeType"] = mimeType
+	}
+	// NUL bytes are valid UTF-8 but a sure sign of binary content
+	if utf8.Valid(data) && !bytes.Contains(data, []byte{0}) {
+		contents["text"] = string(data)
+	} else {
+		contents["blob"] = base64.StdEncoding.EncodeToString(data)
+	}
+	return contents, nil
+}
+
+// resourceMimeType returns the configured MIME type, or guesses it from the
+// file extension or content
+func resourceMimeType(res *config.Resource, path string, data []byte) string {
+	if res.MimeType != "" {
+		return res.MimeType
+	}
+	if path != "" {
+		if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+			return t
+		}
+	}
+	if len(data) > 0 {
+		return http.DetectContentType(data)
+	}
+	return ""
+}
+
+var templateParamPattern = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// matchURITemplate matches a URI against a template like "notes://{name}",
+// returning the parameter values. Parameters match a single path segment.
+func matchURITemplate(template, uri string) (map[string]string, bool) {
+	var pattern strings.Builder
+	var names []string
+	last := 0
+	for _, loc := range templateParamPattern.FindAllStringSubmatchIndex(template, -1) {
+		pattern.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		pattern.WriteString(`([^/]+)`)
+		names = append(names, template[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+
+	re, err := regexp.Compile("^" + pattern.String() + "$")
+	if err != nil {
+		return nil, false
+	}
+	m := re.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, false
+	}
+
+	params := make(map[string]string, len(names))
+	for i, name := range names {
+		value, err := url.PathUnescape(m[i+1])
+		if err != nil || value == ".." || strings.ContainsAny(value, `/\`) {
+			return nil, false
+		}
+		params[name] = value
+	}
+	return params, true
+}
+
+func expandPlaceholders(template string, params map[string]string) string {
+	for k, v := range params {
+		template = strings.ReplaceAll(template, "{{"+k+"}}", v)
+	}
+	return template
+}
+
+func fileURI(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return "file://" + filepath.ToSlash(abs)
+}
+
+func errorResponse(req *tunnel.MCPRequest, code int, message string) *tunnel.MCPResponse {
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Error: &tunnel.MCPError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+// resourceWatcher turns file system events into resource notifications
+type resourceWatcher struct {
+	mu            sync.Mutex
+	watcher       *fsnotify.Watcher
+	dirs          map[string]bool
+	subscriptions map[string]string // URI -> file path
+	globs         []string
+	timers        map[string]*time.Timer
+	onUpdated     func(uri string)
+	onListChanged func()
+}
+
+func newResourceWatcher(onUpdated func(uri string), onListChanged func()) *resourceWatcher {
+	w := &resourceWatcher{
+		dirs:          make(map[string]bool),
+		subscriptions: make(map[string]string),
+		timers:        make(map[string]*time.Timer),
+		onUpdated:     onUpdated,
+		onListChanged: onListChanged,
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Warn("Resource notifications disabled", "error", err)
+		return w
+	}
+	w.watcher = watcher
+	go w.run()
+	return w
+}
+
+// watchDir starts watching a directory; callers hold w.mu
+func (w *resourceWatcher) watchDir(dir string) {
+	if w.watcher == nil || w.dirs[dir] {
+		return
+	}
+	if err := w.watcher.Add(dir); err == nil {
+		w.dirs[dir] = true
+	}
+}
+
+func (w *resourceWatcher) subscribe(uri, path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	w.subscriptions[uri] = abs
+	w.watchDir(filepath.Dir(abs))
+}
+
+func (w *resourceWatcher) unsubscribe(uri string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.subscriptions, uri)
+}
+
+// releaseSubscription stops watching a resource once no session is
+// subscribed to it
+func (s *Server) releaseSubscription(uri string) {
+	for _, sess := range s.sessions.all() {
+		sess.mu.Lock()
+		subscribed := sess.subscriptions[uri]
+		sess.mu.Unlock()
+		if subscribed {
+			return
+		}
+	}
+	s.resourceWatcher.unsubscribe(uri)
+}
+
+// setGlobs watches the directories of glob resources so new and removed files
+// produce list_changed notifications
+func (w *resourceWatcher) setGlobs(resources []config.Resource) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.globs = nil
+	for _, res := range resources {
+		if res.Glob == "" {
+			continue
+		}
+		w.globs = append(w.globs, res.Glob)
+		if dir := filepath.Dir(res.Glob); !strings.ContainsAny(dir, "*?[") {
+			w.watchDir(dir)
+		}
+	}
+}
+
+func (w *resourceWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event)
+		case _, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func (w *resourceWatcher) handleEvent(event fsnotify.Event) {
+	path := filepath.Clean(event.Name)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for uri, subscribed := range w.subscriptions {
+		if subscribed == path && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+			uri := uri
+			w.debounce("updated:"+uri, func() { w.onUpdated(uri) })
+		}
+	}
+
+	if event.Op&(fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+		for _, glob := range w.globs {
+			if ok, _ := filepath.Match(glob, path); ok {
+				w.debounce("list", w.onListChanged)
+				break
+			}
+		}
+	}
+}
+
+// debounce coalesces bursts of events (editors often write a file several
+// times per save); callers hold w.mu
+func (w *resourceWatcher) debounce(key string, fn func()) {
+	if t, ok := w.timers[key]; ok {
+		t.Stop()
+	}
+	w.timers[key] = time.AfterFunc(100*time.Millisecond, func() {
+		w.mu.Lock()
+		delete(w.timers, key)
+		w.mu.Unlock()
+		fn()
+	})
+}
+
+func (w *resourceWatcher) close() {
+	if w.watcher != nil {
+		w.watcher.Close()
+	}
+}
@@ -3,37 +3,130 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"reflect"
 	"sync"
+	"time"
 
+	"github.com/gantz-ai/gantz-cli/internal/audit"
 	"github.com/gantz-ai/gantz-cli/internal/config"
 	"github.com/gantz-ai/gantz-cli/internal/executor"
+	"github.com/gantz-ai/gantz-cli/internal/keys"
+	"github.com/gantz-ai/gantz-cli/internal/metrics"
+	"github.com/gantz-ai/gantz-cli/internal/oauth"
+	"github.com/gantz-ai/gantz-cli/internal/secrets"
+	"github.com/gantz-ai/gantz-cli/internal/trace"
 	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+	"github.com/gantz-ai/gantz-cli/internal/upstream"
 )
 
+// maxRequestBody is the largest HTTP request body the server reads
+const maxRequestBody = 4 << 20
+
 // Server implements MCP protocol handler
 type Server struct {
-	config       *config.Config
-	executor     *executor.Executor
-	httpExecutor *executor.HTTPExecutor
-	mu           sync.RWMutex
+	config          *config.Config
+	executor        *executor.Executor
+	httpExecutor    *executor.HTTPExecutor
+	graphqlExecutor *executor.GraphQLExecutor
+	upstreams       *upstream.Manager
+	resourceWatcher *resourceWatcher
+	sessions        sessions
+	authToken       string
+	keyStore        *keys.Store
+	oauth           *oauth.Validator
+	audit           *audit.Logger
+	secrets         *secrets.Resolver
+	approver        Approver
+	clientRequests  clientRequests
+	helper          *helperSocket
+	helperOnce      sync.Once
+	completions     completionCache
+	limits          limiter
+	mu              sync.RWMutex
 }
 
 // NewServer creates a new MCP server
 func NewServer(cfg *config.Config) *Server {
-	return &Server{
-		config:       cfg,
-		executor:     executor.NewExecutor(),
-		httpExecutor: executor.NewHTTPExecutor(),
+	s := &Server{
+		config:          cfg,
+		executor:        executor.NewExecutor(),
+		httpExecutor:    executor.NewHTTPExecutor(),
+		graphqlExecutor: executor.NewGraphQLExecutor(),
+		upstreams:       upstream.NewManager(),
+		secrets:         secrets.NewResolver(cfg.Secrets),
 	}
+	s.setOAuth(cfg.Auth.OAuth)
+	s.setAudit(cfg.Audit)
+	s.upstreams.OnToolsChanged(func() {
+		s.broadcast("notifications/tools/list_changed", nil)
+	})
+	s.upstreams.Update(cfg.Upstreams)
+	s.resourceWatcher = newResourceWatcher(
+		s.notifySubscribers,
+		func() {
+			s.broadcast("notifications/resources/list_changed", nil)
+		},
+	)
+	s.resourceWatcher.setGlobs(cfg.Resources)
+	return s
 }
 
-// UpdateConfig updates the server configuration (for hot-reload)
-func (s *Server) UpdateConfig(cfg *config.Config) {
+// UpdateConfig updates the server configuration (for hot-reload), notifies
+// connected clients of changes and returns the difference in tools
+func (s *Server) UpdateConfig(cfg *config.Config) config.ToolDiff {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	old := s.config
 	s.config = cfg
+	if !reflect.DeepEqual(old.Auth.OAuth, cfg.Auth.OAuth) {
+		s.setOAuth(cfg.Auth.OAuth)
+	}
+	if old.Audit != cfg.Audit {
+		s.setAudit(cfg.Audit)
+	}
+	if !reflect.DeepEqual(old.Secrets, cfg.Secrets) {
+		s.secrets = secrets.NewResolver(cfg.Secrets)
+	}
+	s.mu.Unlock()
+	s.upstreams.Update(cfg.Upstreams)
+
+	diff := config.DiffTools(old, cfg)
+	if !diff.Empty() {
+		s.broadcast("notifications/tools/list_changed", nil)
+	}
+	s.Log("info", "gantz", map[string]interface{}{
+		"event":   "config_reloaded",
+		"added":   diff.Added,
+		"removed": diff.Removed,
+		"changed": diff.Changed,
+	})
+
+	s.resourceWatcher.setGlobs(cfg.Resources)
+	if !reflect.DeepEqual(old.Resources, cfg.Resources) {
+		s.broadcast("notifications/resources/list_changed", nil)
+	}
+	if !reflect.DeepEqual(old.Prompts, cfg.Prompts) {
+		s.broadcast("notifications/prompts/list_changed", nil)
+	}
+	return diff
+}
+
+// Close stops upstream servers and closes pooled connections
+func (s *Server) Close() {
+	s.upstreams.Close()
+	s.executor.Close()
+	s.resourceWatcher.close()
+	s.mu.Lock()
+	s.setAudit(config.AuditConfig{})
+	s.mu.Unlock()
+	if s.helper != nil {
+		s.helper.close()
+	}
 }
 
 // GetConfig returns the current config (thread-safe)
@@ -44,14 +137,69 @@ func (s *Server) GetConfig() *config.Config {
 }
 
 // HandleRequest processes an MCP request (implements tunnel.MCPHandler)
-func (s *Server) HandleRequest(req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+func (s *Server) HandleRequest(ctx context.Context, req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+	sess, rejected := s.sessionFor(tunnel.SessionFromContext(ctx), req)
+
+	// Notifications get no response
+	if req.ID == nil {
+		if rejected == nil {
+			s.handleNotification(sess, req)
+		}
+		return nil, nil
+	}
+	if rejected != nil {
+		return rejected, nil
+	}
+
+	// Requests relayed through the tunnel already have a span
+	kind := trace.Internal
+	if trace.FromContext(ctx) == nil {
+		kind = trace.Server
+	}
+	ctx = trace.WithRemoteParent(ctx, tunnel.MetaTraceparent(req.Params))
+	ctx, span := trace.Start(ctx, req.Method, kind)
+	span.SetAttr("rpc.system", "jsonrpc")
+	span.SetAttr("rpc.method", req.Method)
+	if sess != nil {
+		span.SetAttr("mcp.session.id", sess.id)
+	}
+	resp, err := s.dispatch(ctx, sess, req)
+	if err != nil {
+		span.Fail(err.Error())
+	} else if resp != nil && resp.Error != nil {
+		span.Fail(resp.Error.Message)
+	}
+	span.End()
+	return resp, err
+}
+
+// dispatch calls the handler for a request's method
+func (s *Server) dispatch(ctx context.Context, sess *session, req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
 	switch req.Method {
 	case "initialize":
-		return s.handleInitialize(req)
+		return s.handleInitialize(sess, req)
 	case "tools/list":
-		return s.handleToolsList(req)
+		return s.handleToolsList(ctx, req)
 	case "tools/call":
-		return s.handleToolsCall(req)
+		return s.handleToolsCall(ctx, sess, req)
+	case "resources/list":
+		return s.handleResourcesList(req)
+	case "resources/templates/list":
+		return s.handleResourcesTemplatesList(req)
+	case "resources/read":
+		return s.handleResourcesRead(ctx, sess, req)
+	case "resources/subscribe":
+		return s.handleResourcesSubscribe(sess, req)
+	case "resources/unsubscribe":
+		return s.handleResourcesUnsubscribe(sess, req)
+	case "prompts/list":
+		return s.handlePromptsList(req)
+	case "prompts/get":
+		return s.handlePromptsGet(ctx, sess, req)
+	case "completion/complete":
+		return s.handleComplete(ctx, sess, req)
+	case "logging/setLevel":
+		return s.handleLoggingSetLevel(sess, req)
 	case "ping":
 		return s.handlePing(req)
 	default:
@@ -66,29 +214,67 @@ func (s *Server) HandleRequest(req *tunnel.MCPRequest) (*tunnel.MCPResponse, err
 	}
 }
 
-func (s *Server) handleInitialize(req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+// handleNotification processes a notification from a client
+func (s *Server) handleNotification(sess *session, req *tunnel.MCPRequest) {
+	switch req.Method {
+	case "notifications/initialized":
+		if sess != nil {
+			sess.mu.Lock()
+			sess.ready = true
+			sess.mu.Unlock()
+		}
+	case "notifications/cancelled", "notifications/roots/list_changed":
+		// Nothing to do yet
+	}
+}
+
+func (s *Server) handleInitialize(sess *session, req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
 	cfg := s.GetConfig()
+
+	var params initializeParams
+	json.Unmarshal(req.Params, &params)
+	version := negotiateVersion(params.ProtocolVersion)
+	if sess != nil {
+		sess.start(version, &params)
+	}
+
 	return &tunnel.MCPResponse{
 		JSONRPC: "2.0",
 		ID:      req.ID,
 		Result: map[string]interface{}{
-			"protocolVersion": "2024-11-05",
+			"protocolVersion": version,
 			"serverInfo": map[string]interface{}{
 				"name":    cfg.Name,
 				"version": cfg.Version,
 			},
 			"capabilities": map[string]interface{}{
-				"tools": map[string]interface{}{},
+				"tools": map[string]interface{}{
+					"listChanged": true,
+				},
+				"resources": map[string]interface{}{
+					"subscribe":   true,
+					"listChanged": true,
+				},
+				"prompts": map[string]interface{}{
+					"listChanged": true,
+				},
+				"logging":     map[string]interface{}{},
+				"completions": map[string]interface{}{},
 			},
 		},
 	}, nil
 }
 
-func (s *Server) handleToolsList(req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+func (s *Server) handleToolsList(ctx context.Context, req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
 	cfg := s.GetConfig()
 	tools := make([]map[string]interface{}, 0, len(cfg.Tools))
 
 	for _, tool := range cfg.Tools {
+		// API keys only see the tools they may call
+		if !toolAllowed(ctx, tool.Name, tool.Tags) {
+			continue
+		}
+
 		// Build JSON Schema for parameters
 		properties := make(map[string]interface{})
 		required := []string{}
@@ -116,11 +302,36 @@ func (s *Server) handleToolsList(req *tunnel.MCPRequest) (*tunnel.MCPResponse, e
 			inputSchema["required"] = required
 		}
 
-		tools = append(tools, map[string]interface{}{
+		entry := map[string]interface{}{
 			"name":        tool.Name,
 			"description": tool.Description,
 			"inputSchema": inputSchema,
-		})
+		}
+		if tool.Title != "" {
+			entry["title"] = tool.Title
+		}
+		if annotations := toolAnnotations(&tool); len(annotations) > 0 {
+			entry["annotations"] = annotations
+		}
+		if len(tool.Tags) > 0 || tool.Category != "" {
+			meta := map[string]interface{}{}
+			if len(tool.Tags) > 0 {
+				meta["tags"] = tool.Tags
+			}
+			if tool.Category != "" {
+				meta["category"] = tool.Category
+			}
+			entry["_meta"] = meta
+		}
+		tools = append(tools, entry)
+	}
+
+	// Local tools take precedence over upstream tools with the same name
+	for _, tool := range s.upstreams.Tools() {
+		if cfg.GetTool(tool.Name) != nil || !toolAllowed(ctx, tool.Name, nil) {
+			continue
+		}
+		tools = append(tools, tool.Def)
 	}
 
 	return &tunnel.MCPResponse{
@@ -132,14 +343,41 @@ func (s *Server) handleToolsList(req *tunnel.MCPRequest) (*tunnel.MCPResponse, e
 	}, nil
 }
 
+// toolAnnotations converts a tool's configured hints to MCP annotations
+func toolAnnotations(tool *config.Tool) map[string]interface{} {
+	annotations := map[string]interface{}{}
+	if tool.Title != "" {
+		annotations["title"] = tool.Title
+	}
+	if a := tool.Annotations; a != nil {
+		for name, hint := range map[string]*bool{
+			"readOnlyHint":    a.ReadOnly,
+			"destructiveHint": a.Destructive,
+			"idempotentHint":  a.Idempotent,
+			"openWorldHint":   a.OpenWorld,
+		} {
+			if hint != nil {
+				annotations[name] = *hint
+			}
+		}
+	}
+	return annotations
+}
+
 type toolCallParams struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments"`
 }
 
-func (s *Server) handleToolsCall(req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+func (s *Server) handleToolsCall(ctx context.Context, sess *session, req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
+	// Arguments must decode as an object before anything else happens
+	_, check := trace.Start(ctx, "validate arguments", trace.Internal)
 	var params toolCallParams
-	if err := json.Unmarshal(req.Params, &params); err != nil {
+	err := json.Unmarshal(req.Params, &params)
+	check.SetAttr("gantz.tool", params.Name)
+	if err != nil {
+		check.Fail("invalid params")
+		check.End()
 		return &tunnel.MCPResponse{
 			JSONRPC: "2.0",
 			ID:      req.ID,
@@ -149,11 +387,58 @@ func (s *Server) handleToolsCall(req *tunnel.MCPRequest) (*tunnel.MCPResponse, e
 			},
 		}, nil
 	}
+	check.End()
 
 	// Find tool
 	cfg := s.GetConfig()
 	tool := cfg.GetTool(params.Name)
+
+	// Every call is recorded in the audit log, whether it runs or not
+	red := newRedactor(tool, params.Arguments)
+	rec := s.auditRecord(ctx, sess, params.Name)
+	span := trace.FromContext(ctx)
+	span.SetName("tools/call " + params.Name)
+	span.SetAttr("gantz.tool", params.Name)
+	defer func() {
+		s.writeAudit(rec, red, params.Arguments)
+		observeToolCall(rec)
+		span.SetAttr("gantz.status", rec.Status)
+		if rec.Status != "ok" {
+			span.Fail(rec.Status)
+		}
+	}()
+
 	if tool == nil {
+		if upstreamTool := s.upstreams.GetTool(params.Name); upstreamTool != nil {
+			rec.Upstream = upstreamTool.Upstream
+			rec.Command = upstreamTool.Original
+			if !toolAllowed(ctx, upstreamTool.Name, nil) {
+				return s.denyToolCall(sess, req, rec, toolDeniedReason(ctx)), nil
+			}
+			if limited := s.checkRateLimit(ctx, sess, req, upstreamTool.Name); limited != nil {
+				rec.Status = "rate_limited"
+				return limited, nil
+			}
+			if cfg.NeedsConfirm(nil, upstreamReadOnly(upstreamTool)) {
+				args, _ := json.MarshalIndent(params.Arguments, "", "  ")
+				approval := &ApprovalRequest{
+					Tool:      upstreamTool.Name,
+					Action:    fmt.Sprintf("%s on upstream %s\n\narguments: %s", upstreamTool.Original, upstreamTool.Upstream, args),
+					Arguments: params.Arguments,
+				}
+				if reason := s.requestApproval(approval); reason != "" {
+					return s.denyToolCall(sess, req, rec, reason), nil
+				}
+			}
+			release, busy := s.acquireSlots(ctx, sess, req, upstreamTool.Name, 0)
+			if busy != nil {
+				rec.Status = "busy"
+				return busy, nil
+			}
+			defer release()
+			return s.callUpstreamTool(ctx, sess, req, rec, upstreamTool, params.Arguments), nil
+		}
+		rec.Status = "not_found"
 		return &tunnel.MCPResponse{
 			JSONRPC: "2.0",
 			ID:      req.ID,
@@ -164,31 +449,131 @@ func (s *Server) handleToolsCall(req *tunnel.MCPRequest) (*tunnel.MCPResponse, e
 		}, nil
 	}
 
+	logger := "tool/" + tool.Name
+	rec.Command = red.String(executor.Describe(tool, params.Arguments))
+
+	if !toolAllowed(ctx, tool.Name, tool.Tags) {
+		return s.denyToolCall(sess, req, rec, toolDeniedReason(ctx)), nil
+	}
+	if limited := s.checkRateLimit(ctx, sess, req, tool.Name); limited != nil {
+		rec.Status = "rate_limited"
+		return limited, nil
+	}
+
+	// Wait for a human to approve calls that need confirmation
+	if cfg.NeedsConfirm(tool.Confirm, tool.IsReadOnly()) {
+		approval := &ApprovalRequest{
+			Tool:      tool.Name,
+			Action:    rec.Command,
+			Arguments: red.Arguments(params.Arguments),
+		}
+		if reason := s.requestApproval(approval); reason != "" {
+			return s.denyToolCall(sess, req, rec, reason), nil
+		}
+	}
+
+	// Wait for a free slot if too many calls are running
+	release, busy := s.acquireSlots(ctx, sess, req, tool.Name, tool.Concurrency)
+	if busy != nil {
+		rec.Status = "busy"
+		return busy, nil
+	}
+	defer release()
+
+	// Resolve secret references on every call, so rotated secrets apply at once
+	s.mu.RLock()
+	resolver := s.secrets
+	s.mu.RUnlock()
+	run, values, err := resolver.ResolveTool(ctx, tool)
+	if err != nil {
+		slog.Warn("Secret lookup failed", "tool", tool.Name, "error", err)
+		rec.Status = "failed"
+		rec.Error = err.Error()
+		return &tunnel.MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result: map[string]interface{}{
+				"content": []map[string]interface{}{
+					{"type": "text", "text": fmt.Sprintf("Error: %v", err)},
+				},
+				"isError": true,
+			},
+		}, nil
+	}
+	red.addValues(values)
+
 	// Execute tool
-	fmt.Printf("  → Executing tool: %s\n", params.Name)
+	metrics.ToolsInFlight.Inc()
+	defer metrics.ToolsInFlight.Dec()
+	slog.Info("Executing tool", "tool", params.Name)
+	s.logSession(sess, "info", logger, map[string]interface{}{
+		"event":     "tool_started",
+		"arguments": red.Arguments(params.Arguments),
+	})
+
+	// Let local scripts reach the client through 'gantz ask'
+	if !tool.IsHTTP() && !tool.IsGraphQL() {
+		env, done := s.helperEnv(ctx, sess)
+		defer done()
+		ctx = executor.WithEnv(ctx, env)
+	}
+
+	// Forward stderr to the client line by line while the script runs
+	ctx = executor.WithStderrHandler(ctx, func(line string) {
+		s.logSession(sess, "info", logger, map[string]interface{}{
+			"event": "stderr",
+			"line":  red.String(line),
+		})
+	})
 
 	var result *executor.Result
 	if tool.IsHTTP() {
-		result = s.httpExecutor.Execute(context.Background(), tool, params.Arguments)
+		result = s.httpExecutor.Execute(ctx, run, params.Arguments)
+	} else if tool.IsGraphQL() {
+		result = s.graphqlExecutor.Execute(ctx, run, params.Arguments)
 	} else {
-		result = s.executor.Execute(context.Background(), tool, params.Arguments)
+		result = s.executor.Execute(ctx, run, params.Arguments)
 	}
 
-	fmt.Printf("  ← Completed in %v (exit=%d)\n", result.Duration, result.ExitCode)
+	if result.Error == nil {
+		slog.Info("Tool completed", "tool", tool.Name, "duration", result.Duration, "exit_code", result.ExitCode)
+	} else {
+		slog.Warn("Tool failed", "tool", tool.Name, "duration", result.Duration, "exit_code", result.ExitCode, "error", red.String(result.Error.Error()))
+	}
+	finished := map[string]interface{}{
+		"event":      "tool_finished",
+		"exitCode":   result.ExitCode,
+		"durationMs": result.Duration.Milliseconds(),
+	}
+	level := "info"
+	if result.Error != nil {
+		finished["error"] = red.String(result.Error.Error())
+		level = "warning"
+	}
+	s.logSession(sess, level, logger, finished)
+	sess.recordToolCall(result.ExitCode != 0)
 
-	// Build response content
-	content := []map[string]interface{}{}
+	rec.Status = "ok"
+	if result.ExitCode != 0 {
+		rec.Status = "failed"
+	}
+	rec.ExitCode = result.ExitCode
+	rec.DurationMs = result.Duration.Milliseconds()
+	rec.OutputBytes = len(result.Output)
+	if result.Error != nil {
+		rec.Error = result.Error.Error()
+	}
 
+	// Build response content
+	text := result.Output
 	if result.Error != nil && result.Output == "" {
-		content = append(content, map[string]interface{}{
-			"type": "text",
-			"text": fmt.Sprintf("Error: %v", result.Error),
-		})
-	} else {
-		content = append(content, map[string]interface{}{
-			"type": "text",
-			"text": result.Output,
-		})
+		text = fmt.Sprintf("Error: %v", result.Error)
+	}
+	if tool.RedactOutput {
+		text = red.String(text)
+	}
+	content := []map[string]interface{}{
+		{"type": "text", "text": text},
 	}
 
 	return &tunnel.MCPResponse{
@@ -201,6 +586,87 @@ func (s *Server) handleToolsCall(req *tunnel.MCPRequest) (*tunnel.MCPResponse, e
 	}, nil
 }
 
+// denyToolCall reports a refused tool call to the terminal and the client
+func (s *Server) denyToolCall(sess *session, req *tunnel.MCPRequest, rec *audit.Record, reason string) *tunnel.MCPResponse {
+	tool := rec.Tool
+	rec.Status = "denied"
+	rec.Error = reason
+	slog.Warn("Denied tool", "tool", tool, "reason", reason)
+	s.logSession(sess, "warning", "tool/"+tool, map[string]interface{}{
+		"event":  "tool_denied",
+		"reason": reason,
+	})
+	return deniedResponse(req, tool, reason)
+}
+
+// upstreamReadOnly returns the read-only hint an upstream server gave a tool
+func upstreamReadOnly(tool *upstream.Tool) bool {
+	annotations, _ := tool.Def["annotations"].(map[string]interface{})
+	readOnly, _ := annotations["readOnlyHint"].(bool)
+	return readOnly
+}
+
+// callUpstreamTool forwards a tools/call to the upstream that owns the tool
+func (s *Server) callUpstreamTool(ctx context.Context, sess *session, req *tunnel.MCPRequest, rec *audit.Record, tool *upstream.Tool, args map[string]interface{}) *tunnel.MCPResponse {
+	slog.Info("Executing tool", "tool", tool.Name, "upstream", tool.Upstream)
+	start := time.Now()
+	metrics.ToolsInFlight.Inc()
+	defer metrics.ToolsInFlight.Dec()
+
+	logger := "tool/" + tool.Name
+	s.logSession(sess, "info", logger, map[string]interface{}{
+		"event":     "tool_started",
+		"upstream":  tool.Upstream,
+		"arguments": args,
+	})
+
+	result, err := s.upstreams.CallTool(ctx, tool, args)
+	if err != nil {
+		slog.Warn("Tool failed", "tool", tool.Name, "upstream", tool.Upstream, "duration", time.Since(start), "error", err)
+		sess.recordToolCall(true)
+		rec.Status = "failed"
+		rec.DurationMs = time.Since(start).Milliseconds()
+		rec.Error = err.Error()
+		s.logSession(sess, "warning", logger, map[string]interface{}{
+			"event":      "tool_finished",
+			"durationMs": time.Since(start).Milliseconds(),
+			"error":      err.Error(),
+		})
+		return &tunnel.MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result: map[string]interface{}{
+				"content": []map[string]interface{}{
+					{"type": "text", "text": fmt.Sprintf("Error: %v", err)},
+				},
+				"isError": true,
+			},
+		}
+	}
+
+	slog.Info("Tool completed", "tool", tool.Name, "upstream", tool.Upstream, "duration", time.Since(start))
+	sess.recordToolCall(false)
+	rec.Status = "ok"
+	rec.ExitCode = 0
+	rec.DurationMs = time.Since(start).Milliseconds()
+	rec.OutputBytes = len(result)
+	var outcome struct {
+		IsError bool `json:"isError"`
+	}
+	if json.Unmarshal(result, &outcome) == nil && outcome.IsError {
+		rec.Status = "failed"
+	}
+	s.logSession(sess, "info", logger, map[string]interface{}{
+		"event":      "tool_finished",
+		"durationMs": time.Since(start).Milliseconds(),
+	})
+	return &tunnel.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
 func (s *Server) handlePing(req *tunnel.MCPRequest) (*tunnel.MCPResponse, error) {
 	return &tunnel.MCPResponse{
 		JSONRPC: "2.0",
@@ -211,6 +677,12 @@ func (s *Server) handlePing(req *tunnel.MCPRequest) (*tunnel.MCPResponse, error)
 
 // ListenAndServe starts HTTP server for local mode
 func (s *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, s.Handler())
+}
+
+// Handler returns the local HTTP endpoints: /mcp, /sse, the OAuth protected
+// resource metadata and /metrics
+func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 
 	// SSE endpoint for MCP
@@ -219,28 +691,93 @@ func (s *Server) ListenAndServe(addr string) error {
 	// Simple JSON-RPC endpoint
 	mux.HandleFunc("/mcp", s.handleHTTP)
 
-	return http.ListenAndServe(addr, mux)
+	// OAuth protected resource metadata, at the root and per resource path
+	mux.HandleFunc("/.well-known/oauth-protected-resource", s.handleResourceMetadata)
+	mux.HandleFunc("/.well-known/oauth-protected-resource/", s.handleResourceMetadata)
+
+	// Prometheus metrics
+	mux.Handle("/metrics", metrics.Handler())
+
+	return mux
 }
 
 func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+	// Clients identify their session with the Mcp-Session-Id header, or with
+	// the endpoint URL they got from /sse
+	id := r.Header.Get("Mcp-Session-Id")
+	if id == "" {
+		id = r.URL.Query().Get("session")
+	}
+
+	ctx, ok := s.authenticateHTTP(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		if id == "" || s.sessions.get(id) == nil {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		s.CloseSession(id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var req tunnel.MCPRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read request", http.StatusBadRequest)
 		return
 	}
 
-	resp, _ := s.HandleRequest(&req)
+	if id != "" {
+		sess := s.sessions.get(id)
+		if sess == nil {
+			// Expired or closed; the client has to initialize again
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		// Requests of an SSE session can be answered with server-initiated
+		// requests on its stream
+		if sess.peer != nil {
+			ctx = tunnel.WithPeer(ctx, sess.peer)
+		}
+	}
+	ref := &tunnel.SessionRef{ID: id}
+	ctx = tunnel.WithSession(ctx, ref)
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ctx = tunnel.WithClientIP(ctx, host)
+	}
+
+	reply := tunnel.HandlePayload(ctx, s, body)
+	if ref.ID != "" {
+		w.Header().Set("Mcp-Session-Id", ref.ID)
+	}
+	if reply == nil {
+		// Only notifications or responses were sent
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	w.Write(reply)
 }
 
 func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.authenticateHTTP(w, r); !ok {
+		return
+	}
+
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		http.Error(w, "SSE not supported", http.StatusInternalServerError)
@@ -251,10 +788,18 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
+	// The session lives as long as the stream
+	stream := &sseStream{w: w, flusher: flusher}
+	sess := newSession()
+	sess.notifier = stream
+	sess.peer = stream
+	sess.live = true
+	s.sessions.add(sess)
+	defer s.CloseSession(sess.id)
+
 	// Send endpoint info
-	fmt.Fprintf(w, "event: endpoint\ndata: /mcp\n\n")
-	flusher.Flush()
+	stream.send("endpoint", "/mcp?session="+sess.id)
 
-	// Keep connection open
+	// Deliver notifications until the client disconnects
 	<-r.Context().Done()
 }
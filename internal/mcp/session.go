@@ -0,0 +1,260 @@
+package mcp
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/tunnel"
+)
+
+// supportedProtocolVersions lists the MCP versions gantz speaks, newest first
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// sessionIdleTimeout is how long a session without a live connection is kept
+// after its last request
+const sessionIdleTimeout = time.Hour
+
+// session is the state of one client, from initialize until it disconnects
+type session struct {
+	id      string
+	started time.Time
+	peer    tunnel.Peer // set for SSE sessions, which can receive requests
+
+	mu              sync.Mutex
+	initialized     bool // initialize has been answered
+	ready           bool // client sent notifications/initialized
+	protocolVersion string
+	clientName      string
+	clientVersion   string
+	capabilities    map[string]interface{}
+	notifier        tunnel.Notifier
+	live            bool // notifier is a connection that ends with the session
+	logLevel        int
+	subscriptions   map[string]bool
+	lastSeen        time.Time
+	requests        int
+	toolCalls       int
+	toolErrors      int
+}
+
+func newSession() *session {
+	now := time.Now()
+	return &session{
+		id:            randomToken(),
+		started:       now,
+		lastSeen:      now,
+		logLevel:      logSeverity(defaultLogLevel),
+		subscriptions: make(map[string]bool),
+	}
+}
+
+// isReady reports whether the client finished the handshake with
+// notifications/initialized; until then it is sent no requests
+func (sess *session) isReady() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.ready
+}
+
+// hasCapability reports whether the client declared a capability
+func (sess *session) hasCapability(name string) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	_, ok := sess.capabilities[name]
+	return ok
+}
+
+func (sess *session) notify(n *tunnel.MCPNotification) {
+	sess.mu.Lock()
+	notifier := sess.notifier
+	initialized := sess.initialized
+	sess.mu.Unlock()
+	if notifier != nil && initialized {
+		notifier.Notify(n)
+	}
+}
+
+func (sess *session) recordToolCall(failed bool) {
+	if sess == nil {
+		return
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.toolCalls++
+	if failed {
+		sess.toolErrors++
+	}
+}
+
+// client names the client for terminal output; callers hold sess.mu
+func (sess *session) client() string {
+	switch {
+	case sess.clientName == "":
+		return "unknown client"
+	case sess.clientVersion == "":
+		return sess.clientName
+	default:
+		return sess.clientName + " " + sess.clientVersion
+	}
+}
+
+// sessions are the connected clients by session ID
+type sessions struct {
+	mu   sync.Mutex
+	byID map[string]*session
+}
+
+func (ss *sessions) add(sess *session) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.byID == nil {
+		ss.byID = make(map[string]*session)
+	}
+	ss.byID[sess.id] = sess
+}
+
+func (ss *sessions) get(id string) *session {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.byID[id]
+}
+
+func (ss *sessions) remove(id string) *session {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	sess := ss.byID[id]
+	delete(ss.byID, id)
+	return sess
+}
+
+func (ss *sessions) all() []*session {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	list := make([]*session, 0, len(ss.byID))
+	for _, sess := range ss.byID {
+		list = append(list, sess)
+	}
+	return list
+}
+
+// expired returns sessions without a live connection that have been idle for
+// longer than sessionIdleTimeout
+func (ss *sessions) expired() []string {
+	var ids []string
+	for _, sess := range ss.all() {
+		sess.mu.Lock()
+		if !sess.live && time.Since(sess.lastSeen) > sessionIdleTimeout {
+			ids = append(ids, sess.id)
+		}
+		sess.mu.Unlock()
+	}
+	return ids
+}
+
+// sessionFor finds the session of a request, enforcing the initialize
+// handshake. It returns a nil session for transports that don't track
+// sessions, and an error response if the request must be rejected.
+func (s *Server) sessionFor(ref *tunnel.SessionRef, req *tunnel.MCPRequest) (*session, *tunnel.MCPResponse) {
+	if ref == nil {
+		return nil, nil
+	}
+	if ref.ID == "" && req.Method != "initialize" {
+		return nil, errorResponse(req, -32600, "Missing session ID; send initialize first and pass its Mcp-Session-Id")
+	}
+
+	var sess *session
+	if ref.ID != "" {
+		sess = s.sessions.get(ref.ID)
+		if sess == nil && req.Method != "initialize" {
+			return nil, errorResponse(req, -32001, "Session not found; send initialize to start a new session")
+		}
+	}
+
+	switch {
+	case req.Method == "initialize":
+		if sess == nil {
+			sess = newSession()
+			s.sessions.add(sess)
+			for _, id := range s.sessions.expired() {
+				s.CloseSession(id)
+			}
+		}
+		ref.ID = sess.id
+		sess.mu.Lock()
+		if !sess.live {
+			sess.notifier = ref.Notifier
+		}
+		sess.mu.Unlock()
+	case req.Method == "ping":
+		// Allowed at any time
+	default:
+		sess.mu.Lock()
+		initialized := sess.initialized
+		sess.mu.Unlock()
+		if !initialized {
+			return nil, errorResponse(req, -32600, "Session not initialized; send initialize first")
+		}
+	}
+
+	if sess != nil {
+		sess.mu.Lock()
+		sess.lastSeen = time.Now()
+		sess.requests++
+		sess.mu.Unlock()
+	}
+	return sess, nil
+}
+
+type initializeParams struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"clientInfo"`
+}
+
+// negotiateVersion picks the protocol version to use: the client's if gantz
+// supports it, otherwise the newest one gantz speaks
+func negotiateVersion(requested string) string {
+	for _, v := range supportedProtocolVersions {
+		if v == requested {
+			return v
+		}
+	}
+	return supportedProtocolVersions[0]
+}
+
+// start records what the client sent in initialize
+func (sess *session) start(version string, p *initializeParams) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.protocolVersion = version
+	sess.clientName = p.ClientInfo.Name
+	sess.clientVersion = p.ClientInfo.Version
+	sess.capabilities = p.Capabilities
+	sess.initialized = true
+
+	slog.Info("Session started", "client", sess.client(), "session", sess.id, "protocol", version)
+}
+
+// CloseSession ends a client session (implements tunnel.MCPHandler)
+func (s *Server) CloseSession(id string) {
+	sess := s.sessions.remove(id)
+	if sess == nil {
+		return
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.initialized {
+		slog.Info("Session ended", "client", sess.client(), "session", sess.id, "requests", sess.requests,
+			"tool_calls", sess.toolCalls, "failed", sess.toolErrors, "duration", time.Since(sess.started).Round(time.Second))
+	}
+
+	// The session is no longer listed, so its subscriptions don't count
+	for uri := range sess.subscriptions {
+		s.releaseSubscription(uri)
+	}
+}
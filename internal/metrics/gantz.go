@@ -0,0 +1,38 @@
+package metrics
+
+// Metrics of gantz. Series are created when first used.
+var (
+	ToolCalls = NewCounterVec("gantz_tool_calls_total",
+		"Tool calls by tool and status (ok, failed, denied, rate_limited, busy or not_found).",
+		"tool", "status")
+	ToolDuration = NewHistogramVec("gantz_tool_duration_seconds",
+		"Duration of tool calls that ran, by tool.",
+		DefaultBuckets, "tool")
+	ToolsInFlight = NewGauge("gantz_tool_calls_in_flight",
+		"Tool calls running now.")
+
+	ExecutionDuration = NewHistogramVec("gantz_execution_duration_seconds",
+		"Time executors took to run a tool, by executor (script, container, ssh, http or graphql) and tool.",
+		DefaultBuckets, "executor", "tool")
+	HTTPRequestDuration = NewHistogramVec("gantz_http_request_duration_seconds",
+		"Latency of requests made by HTTP and GraphQL tools, by tool and response status code (or \"error\").",
+		DefaultBuckets, "tool", "code")
+
+	TunnelConnected = NewGauge("gantz_tunnel_connected",
+		"1 while connected to the relay.")
+	TunnelConnects = NewCounterVec("gantz_tunnel_connects_total",
+		"Attempts to connect to the relay, by result (ok or error).",
+		"result")
+	TunnelReconnects = NewCounter("gantz_tunnel_reconnects_total",
+		"Successful connections to the relay after the first.")
+	TunnelRequests = NewCounterVec("gantz_tunnel_requests_total",
+		"Requests received through the relay, by result (ok, rejected or busy).",
+		"result")
+
+	AuditWriteErrors = NewCounter("gantz_audit_write_errors_total",
+		"Audit records that could not be written.")
+
+	ConfigReloads = NewCounterVec("gantz_config_reloads_total",
+		"Config file reloads, by result (ok or error).",
+		"result")
+)
@@ -0,0 +1,310 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram upper bounds in seconds, from 5ms to 5m
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// metric is a family of series with the same name
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []metric
+)
+
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, m)
+}
+
+// family holds what every metric type shares
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (f *family) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+}
+
+// key joins label values into a map key
+func key(values []string) string {
+	return strings.Join(values, "\x00")
+}
+
+// labelPairs formats labels as {a="1",b="2"}, with extra pairs appended
+func labelPairs(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var parts []string
+	for i, name := range names {
+		parts = append(parts, name+`="`+escape(values[i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		parts = append(parts, extra[i]+`="`+escape(extra[i+1])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	family
+	mu     sync.Mutex
+	series map[string]*Counter
+}
+
+// Counter is a value that only goes up
+type Counter struct {
+	labels []string
+	mu     sync.Mutex
+	value  float64
+}
+
+// NewCounterVec registers a counter with the given label names
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		family: family{name: name, help: help, kind: "counter", labels: labels},
+		series: make(map[string]*Counter),
+	}
+	register(c)
+	return c
+}
+
+// NewCounter registers a counter without labels
+func NewCounter(name, help string) *Counter {
+	return NewCounterVec(name, help).With()
+}
+
+// With returns the counter for the given label values
+func (c *CounterVec) With(values ...string) *Counter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := key(values)
+	s, ok := c.series[k]
+	if !ok {
+		s = &Counter{labels: values}
+		c.series[k] = s
+	}
+	return s
+}
+
+// Inc adds one
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds v, which must not be negative
+func (c *Counter) Add(v float64) {
+	c.mu.Lock()
+	c.value += v
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.header(w)
+	for _, s := range c.sorted() {
+		s.mu.Lock()
+		v := s.value
+		s.mu.Unlock()
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labelPairs(c.labels, s.labels), formatFloat(v))
+	}
+}
+
+func (c *CounterVec) sorted() []*Counter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make([]string, 0, len(c.series))
+	for k := range c.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]*Counter, len(keys))
+	for i, k := range keys {
+		list[i] = c.series[k]
+	}
+	return list
+}
+
+// Gauge is a value that goes up and down
+type Gauge struct {
+	family
+	mu    sync.Mutex
+	value float64
+}
+
+// NewGauge registers a gauge without labels
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{family: family{name: name, help: help, kind: "gauge"}}
+	register(g)
+	return g
+}
+
+// Inc adds one
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec subtracts one
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Add adds v
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.value += v
+	g.mu.Unlock()
+}
+
+// Set sets the value
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.value = v
+	g.mu.Unlock()
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.header(w)
+	g.mu.Lock()
+	v := g.value
+	g.mu.Unlock()
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(v))
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	family
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*Histogram
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	labels  []string
+	buckets []float64
+	mu      sync.Mutex
+	counts  []uint64 // Per bucket, not cumulative
+	count   uint64
+	sum     float64
+}
+
+// NewHistogramVec registers a histogram with the given bucket upper bounds
+// and label names
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		family:  family{name: name, help: help, kind: "histogram", labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*Histogram),
+	}
+	register(h)
+	return h
+}
+
+// With returns the histogram for the given label values
+func (h *HistogramVec) With(values ...string) *Histogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	k := key(values)
+	s, ok := h.series[k]
+	if !ok {
+		s = &Histogram{labels: values, buckets: h.buckets, counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+	}
+	return s
+}
+
+// Observe records a value
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.header(w)
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]*Histogram, len(keys))
+	for i, k := range keys {
+		list[i] = h.series[k]
+	}
+	h.mu.Unlock()
+
+	for _, s := range list {
+		s.mu.Lock()
+		var cumulative uint64
+		for i, bound := range s.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelPairs(h.labels, s.labels, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelPairs(h.labels, s.labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelPairs(h.labels, s.labels), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelPairs(h.labels, s.labels), s.count)
+		s.mu.Unlock()
+	}
+}
+
+// Write writes all metrics in the Prometheus text format
+func Write(out io.Writer) error {
+	registryMu.Lock()
+	list := append([]metric(nil), registry...)
+	registryMu.Unlock()
+
+	w := bufio.NewWriter(out)
+	for _, m := range list {
+		m.write(w)
+	}
+	return w.Flush()
+}
+
+// Handler serves the metrics for Prometheus to scrape
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w)
+	})
+}
@@ -0,0 +1,238 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksMaxAge is how long keys fetched from a URL are used before they
+	// are fetched again
+	jwksMaxAge = time.Hour
+
+	// jwksMinRefresh limits refetching when tokens name unknown key IDs
+	jwksMinRefresh = time.Minute
+)
+
+// jwk is one key of a JSON Web Key Set
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey is a verification key with the ID and algorithm it was published with
+type publicKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// keySet loads verification keys from a JWKS URL or a local file
+type keySet struct {
+	url  string
+	file string
+
+	mu      sync.Mutex
+	keys    []publicKey
+	fetched time.Time
+	modTime time.Time
+}
+
+// lookup returns the keys that may have signed a token with the given key ID
+func (ks *keySet) lookup(kid string) ([]publicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if err := ks.refresh(false); err != nil && ks.keys == nil {
+		return nil, err
+	}
+	keys := ks.match(kid)
+	if len(keys) == 0 && ks.url != "" && time.Since(ks.fetched) > jwksMinRefresh {
+		// The authorization server may have rotated its keys
+		if err := ks.refresh(true); err != nil {
+			return nil, err
+		}
+		keys = ks.match(kid)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no key with ID %q", kid)
+	}
+	return keys, nil
+}
+
+// match returns the keys with an ID, or all keys for tokens without one;
+// callers hold ks.mu
+func (ks *keySet) match(kid string) []publicKey {
+	if kid == "" {
+		return ks.keys
+	}
+	var keys []publicKey
+	for _, k := range ks.keys {
+		if k.kid == kid || k.kid == "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// refresh reloads the keys if they are stale; callers hold ks.mu
+func (ks *keySet) refresh(force bool) error {
+	if ks.file != "" {
+		info, err := os.Stat(ks.file)
+		if err != nil {
+			return fmt.Errorf("read JWKS: %w", err)
+		}
+		if !force && ks.keys != nil && info.ModTime().Equal(ks.modTime) {
+			return nil
+		}
+		data, err := os.ReadFile(ks.file)
+		if err != nil {
+			return fmt.Errorf("read JWKS: %w", err)
+		}
+		keys, err := parseKeys(data)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", ks.file, err)
+		}
+		ks.keys, ks.modTime = keys, info.ModTime()
+		return nil
+	}
+
+	if !force && ks.keys != nil && time.Since(ks.fetched) < jwksMaxAge {
+		return nil
+	}
+	ks.fetched = time.Now()
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(ks.url)
+	if err != nil {
+		return fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch JWKS: %s returned %s", ks.url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("fetch JWKS: %w", err)
+	}
+	keys, err := parseKeys(data)
+	if err != nil {
+		return fmt.Errorf("parse JWKS from %s: %w", ks.url, err)
+	}
+	ks.keys = keys
+	return nil
+}
+
+// parseKeys reads a JWKS document or a PEM encoded public key
+func parseKeys(data []byte) ([]publicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return []publicKey{{key: key}}, nil
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	var keys []publicKey
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		keys = append(keys, publicKey{kid: k.Kid, alg: k.Alg, key: key})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no signing keys")
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
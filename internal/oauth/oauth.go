@@ -0,0 +1,278 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+)
+
+// clockSkew is how far token times may be off from the local clock
+const clockSkew = time.Minute
+
+// ErrInsufficientScope is returned for valid tokens that lack a required scope
+var ErrInsufficientScope = errors.New("token is missing a required scope")
+
+// Claims are the parts of a validated access token gantz uses
+type Claims struct {
+	Subject  string
+	ClientID string
+	Scopes   []string
+	Expires  time.Time
+}
+
+// Validator checks JWT access tokens against an OAuth configuration
+type Validator struct {
+	cfg  *config.OAuthConfig
+	keys *keySet
+}
+
+// NewValidator creates a validator; keys are loaded on first use
+func NewValidator(cfg *config.OAuthConfig) *Validator {
+	return &Validator{
+		cfg:  cfg,
+		keys: &keySet{url: cfg.JWKSURL, file: cfg.JWKSFile},
+	}
+}
+
+// LooksLikeJWT reports whether a bearer token has the shape of a JWT
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Validate verifies a token's signature and claims. It returns
+// ErrInsufficientScope if the token is valid but lacks a required scope.
+func (v *Validator) Validate(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature")
+	}
+
+	keys, err := v.keys.lookup(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, k := range keys {
+		if k.alg != "" && k.alg != header.Alg {
+			continue
+		}
+		if verify(header.Alg, k.key, signed, sig) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+
+	var claims struct {
+		Iss      string          `json:"iss"`
+		Sub      string          `json:"sub"`
+		Aud      json.RawMessage `json:"aud"`
+		Exp      *float64        `json:"exp"`
+		Nbf      *float64        `json:"nbf"`
+		Scope    string          `json:"scope"`
+		Scp      json.RawMessage `json:"scp"`
+		ClientID string          `json:"client_id"`
+		Azp      string          `json:"azp"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims")
+	}
+
+	now := time.Now()
+	if claims.Exp == nil {
+		return nil, fmt.Errorf("token has no expiry")
+	}
+	expires := unixTime(*claims.Exp)
+	if now.After(expires.Add(clockSkew)) {
+		return nil, fmt.Errorf("token has expired")
+	}
+	if claims.Nbf != nil && now.Add(clockSkew).Before(unixTime(*claims.Nbf)) {
+		return nil, fmt.Errorf("token is not valid yet")
+	}
+	if claims.Iss != v.cfg.Issuer {
+		return nil, fmt.Errorf("token was issued by %q, not %q", claims.Iss, v.cfg.Issuer)
+	}
+	if !containsString(stringList(claims.Aud), v.cfg.Audience) {
+		return nil, fmt.Errorf("token is not meant for audience %q", v.cfg.Audience)
+	}
+
+	scopes := strings.Fields(claims.Scope)
+	if len(scopes) == 0 {
+		scopes = stringList(claims.Scp)
+	}
+	result := &Claims{
+		Subject:  claims.Sub,
+		ClientID: claims.ClientID,
+		Scopes:   scopes,
+		Expires:  expires,
+	}
+	if result.ClientID == "" {
+		result.ClientID = claims.Azp
+	}
+
+	for _, required := range v.cfg.RequiredScopes {
+		if !containsString(scopes, required) {
+			return result, ErrInsufficientScope
+		}
+	}
+	return result, nil
+}
+
+// Allows reports whether the scopes of a token grant a tool
+func (v *Validator) Allows(claims *Claims, tool string, tags []string) bool {
+	if len(v.cfg.Scopes) == 0 {
+		return true
+	}
+	for _, scope := range claims.Scopes {
+		if grant, ok := v.cfg.Scopes[scope]; ok && grant.Allows(tool, tags) {
+			return true
+		}
+	}
+	return false
+}
+
+// MetadataURL returns where the protected resource metadata (RFC 9728) of
+// the server is published
+func (v *Validator) MetadataURL() string {
+	u, err := url.Parse(v.cfg.Resource)
+	if err != nil {
+		return ""
+	}
+	u.Path = "/.well-known/oauth-protected-resource" + strings.TrimSuffix(u.Path, "/")
+	u.RawQuery, u.Fragment = "", ""
+	return u.String()
+}
+
+// Metadata returns the protected resource metadata document
+func (v *Validator) Metadata() map[string]interface{} {
+	scopes := append([]string(nil), v.cfg.RequiredScopes...)
+	for scope := range v.cfg.Scopes {
+		if !containsString(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	metadata := map[string]interface{}{
+		"resource":                 v.cfg.Resource,
+		"authorization_servers":    v.cfg.AuthorizationServers,
+		"bearer_methods_supported": []string{"header"},
+	}
+	if len(scopes) > 0 {
+		metadata["scopes_supported"] = scopes
+	}
+	if v.cfg.JWKSURL != "" {
+		metadata["jwks_uri"] = v.cfg.JWKSURL
+	}
+	return metadata
+}
+
+// RequiredScopes returns the scopes every token must have
+func (v *Validator) RequiredScopes() []string {
+	return v.cfg.RequiredScopes
+}
+
+// verify checks a JWS signature made with the given algorithm
+func verify(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		hash = crypto.SHA512
+	case "EdDSA":
+		k, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(k, signed, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") {
+			return rsa.VerifyPKCS1v15(k, hash, digest, sig)
+		}
+		if strings.HasPrefix(alg, "PS") {
+			return rsa.VerifyPSS(k, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if strings.HasPrefix(alg, "ES") && len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			if ecdsa.Verify(k, digest, r, s) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("invalid signature")
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// stringList decodes a claim that is either a string or a list of strings
+func stringList(raw json.RawMessage) []string {
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return list
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil && s != "" {
+		return strings.Fields(s)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
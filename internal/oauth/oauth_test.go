@@ -0,0 +1,348 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "https://tools.example.com/mcp"
+)
+
+// testKey signs tokens and publishes its public half as a JWK
+type testKey struct {
+	alg  string
+	jwk  map[string]string
+	sign func(signed []byte) []byte
+}
+
+func newRSAKey(t *testing.T, kid string) *testKey {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKey{
+		alg: "RS256",
+		jwk: map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"n":   b64(priv.N.Bytes()),
+			"e":   b64(big.NewInt(int64(priv.E)).Bytes()),
+		},
+		sign: func(signed []byte) []byte {
+			digest := sha256.Sum256(signed)
+			sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sig
+		},
+	}
+}
+
+func newECKey(t *testing.T, kid string) *testKey {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKey{
+		alg: "ES256",
+		jwk: map[string]string{
+			"kty": "EC",
+			"kid": kid,
+			"crv": "P-256",
+			"x":   b64(priv.X.FillBytes(make([]byte, 32))),
+			"y":   b64(priv.Y.FillBytes(make([]byte, 32))),
+		},
+		sign: func(signed []byte) []byte {
+			digest := sha256.Sum256(signed)
+			r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		},
+	}
+}
+
+func newEd25519Key(t *testing.T, kid string) *testKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKey{
+		alg: "EdDSA",
+		jwk: map[string]string{
+			"kty": "OKP",
+			"kid": kid,
+			"crv": "Ed25519",
+			"x":   b64(pub),
+		},
+		sign: func(signed []byte) []byte {
+			return ed25519.Sign(priv, signed)
+		},
+	}
+}
+
+// token returns a JWT with the given claims signed by k
+func (k *testKey) token(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": k.alg, "kid": k.jwk["kid"], "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := b64(header) + "." + b64(payload)
+	return signed + "." + b64(k.sign([]byte(signed)))
+}
+
+// writeJWKS publishes the keys in a JWKS file and returns its path
+func writeJWKS(t *testing.T, keys ...*testKey) string {
+	t.Helper()
+	set := map[string]interface{}{"keys": []map[string]string{}}
+	for _, k := range keys {
+		set["keys"] = append(set["keys"].([]map[string]string), k.jwk)
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// validClaims returns claims that pass validation; tests change one at a time
+func validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":       testIssuer,
+		"sub":       "user-1",
+		"aud":       testAudience,
+		"exp":       now.Add(time.Hour).Unix(),
+		"nbf":       now.Add(-time.Minute).Unix(),
+		"scope":     "mcp tools:read",
+		"client_id": "claude",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	keyTypes := []struct {
+		name string
+		new  func(t *testing.T, kid string) *testKey
+	}{
+		{"RSA", newRSAKey},
+		{"EC", newECKey},
+		{"Ed25519", newEd25519Key},
+	}
+
+	tests := []struct {
+		name    string
+		claims  func(c map[string]interface{})
+		forged  bool   // Signed with a key that isn't in the JWKS
+		wantErr string // Empty for a valid token
+		scope   bool   // Valid, but ErrInsufficientScope
+	}{
+		{name: "valid", claims: func(c map[string]interface{}) {}},
+		{name: "audience list", claims: func(c map[string]interface{}) {
+			c["aud"] = []string{"https://other.example.com", testAudience}
+		}},
+		{name: "scp claim", claims: func(c map[string]interface{}) {
+			delete(c, "scope")
+			c["scp"] = []string{"mcp"}
+		}},
+		{name: "bad signature", forged: true, claims: func(c map[string]interface{}) {}, wantErr: "invalid token signature"},
+		{name: "wrong issuer", claims: func(c map[string]interface{}) {
+			c["iss"] = "https://evil.example.com"
+		}, wantErr: "issued by"},
+		{name: "wrong audience", claims: func(c map[string]interface{}) {
+			c["aud"] = "https://other.example.com"
+		}, wantErr: "not meant for audience"},
+		{name: "expired", claims: func(c map[string]interface{}) {
+			c["exp"] = time.Now().Add(-2 * clockSkew).Unix()
+		}, wantErr: "expired"},
+		{name: "expired within clock skew", claims: func(c map[string]interface{}) {
+			c["exp"] = time.Now().Add(-clockSkew / 2).Unix()
+		}},
+		{name: "no expiry", claims: func(c map[string]interface{}) {
+			delete(c, "exp")
+		}, wantErr: "no expiry"},
+		{name: "not valid yet", claims: func(c map[string]interface{}) {
+			c["nbf"] = time.Now().Add(2 * clockSkew).Unix()
+		}, wantErr: "not valid yet"},
+		{name: "missing required scope", claims: func(c map[string]interface{}) {
+			c["scope"] = "tools:read"
+		}, scope: true},
+	}
+
+	for _, kt := range keyTypes {
+		t.Run(kt.name, func(t *testing.T) {
+			key := kt.new(t, "key-1")
+			forger := kt.new(t, "key-1")
+			v := NewValidator(&config.OAuthConfig{
+				Issuer:         testIssuer,
+				Audience:       testAudience,
+				JWKSFile:       writeJWKS(t, key),
+				RequiredScopes: []string{"mcp"},
+			})
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					claims := validClaims()
+					tt.claims(claims)
+					signer := key
+					if tt.forged {
+						signer = forger
+					}
+
+					got, err := v.Validate(signer.token(t, claims))
+					switch {
+					case tt.scope:
+						if !errors.Is(err, ErrInsufficientScope) {
+							t.Fatalf("Validate() error = %v, want ErrInsufficientScope", err)
+						}
+						if got == nil || got.Subject != "user-1" {
+							t.Fatalf("Validate() claims = %+v, want the token's claims", got)
+						}
+					case tt.wantErr != "":
+						if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+							t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+						}
+					default:
+						if err != nil {
+							t.Fatalf("Validate() error = %v", err)
+						}
+						if got.Subject != "user-1" || got.ClientID != "claude" {
+							t.Errorf("Validate() claims = %+v", got)
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestValidateKeySelection(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa")
+	ecKey := newECKey(t, "ec")
+	edKey := newEd25519Key(t, "")
+	v := NewValidator(&config.OAuthConfig{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		JWKSFile: writeJWKS(t, rsaKey, ecKey, edKey),
+	})
+
+	for _, key := range []*testKey{rsaKey, ecKey, edKey} {
+		if _, err := v.Validate(key.token(t, validClaims())); err != nil {
+			t.Errorf("%s token: %v", key.alg, err)
+		}
+	}
+
+	// A token naming one key but signed with another must not verify
+	other := newECKey(t, "rsa")
+	if _, err := v.Validate(other.token(t, validClaims())); err == nil {
+		t.Error("token signed with the wrong key was accepted")
+	}
+	unknown := newRSAKey(t, "rotated")
+	if _, err := v.Validate(unknown.token(t, validClaims())); err == nil {
+		t.Error("token with an unknown key ID was accepted")
+	}
+}
+
+func TestValidateMalformed(t *testing.T) {
+	key := newEd25519Key(t, "key-1")
+	v := NewValidator(&config.OAuthConfig{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		JWKSFile: writeJWKS(t, key),
+	})
+	valid := key.token(t, validClaims())
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"two segments", parts[0] + "." + parts[1]},
+		{"bad header", "!!." + parts[1] + "." + parts[2]},
+		{"bad signature encoding", parts[0] + "." + parts[1] + ".!!"},
+		{"tampered claims", parts[0] + "." + b64([]byte(`{"iss":"`+testIssuer+`","sub":"admin"}`)) + "." + parts[2]},
+		{"unsigned", b64([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Validate(tt.token); err == nil {
+				t.Error("Validate() accepted the token")
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	v := NewValidator(&config.OAuthConfig{
+		Scopes: map[string]config.ToolScope{
+			"tools:read":   {Tools: []string{"get_*", "list_*"}},
+			"tools:deploy": {Tags: []string{"deploy"}},
+			"tools:all":    {Tools: []string{"*"}},
+		},
+	})
+
+	tests := []struct {
+		name   string
+		scopes []string
+		tool   string
+		tags   []string
+		want   bool
+	}{
+		{"name pattern", []string{"tools:read"}, "get_user", nil, true},
+		{"second pattern", []string{"tools:read"}, "list_users", nil, true},
+		{"pattern miss", []string{"tools:read"}, "delete_user", nil, false},
+		{"tag", []string{"tools:deploy"}, "release", []string{"ops", "deploy"}, true},
+		{"tag miss", []string{"tools:deploy"}, "release", []string{"ops"}, false},
+		{"any scope grants", []string{"tools:read", "tools:deploy"}, "release", []string{"deploy"}, true},
+		{"wildcard", []string{"tools:all"}, "delete_user", nil, true},
+		{"unmapped scope", []string{"mcp"}, "get_user", nil, false},
+		{"no scopes", nil, "get_user", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := v.Allows(&Claims{Scopes: tt.scopes}, tt.tool, tt.tags); got != tt.want {
+				t.Errorf("Allows(%v, %q, %v) = %v, want %v", tt.scopes, tt.tool, tt.tags, got, tt.want)
+			}
+		})
+	}
+
+	open := NewValidator(&config.OAuthConfig{})
+	if !open.Allows(&Claims{}, "delete_user", nil) {
+		t.Error("without a scope mapping every token should get every tool")
+	}
+}
@@ -0,0 +1,213 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+)
+
+// commandTimeout bounds how long a command provider may take
+const commandTimeout = 30 * time.Second
+
+// DefaultPasswordEnv is the variable holding the vault password unless a
+// provider sets password_env
+const DefaultPasswordEnv = "GANTZ_VAULT_PASSWORD"
+
+func newProvider(cfg config.SecretProvider) (Provider, error) {
+	switch cfg.Type {
+	case "env":
+		return envProvider{}, nil
+	case "dotenv":
+		return &fileProvider{path: cfg.File, parse: parseDotenv}, nil
+	case "keyring":
+		return &storeProvider{store: OpenKeyring(cfg.File)}, nil
+	case "vault":
+		passwordEnv := cfg.PasswordEnv
+		if passwordEnv == "" {
+			passwordEnv = DefaultPasswordEnv
+		}
+		password := os.Getenv(passwordEnv)
+		if password == "" {
+			return nil, fmt.Errorf("set %s to the vault password", passwordEnv)
+		}
+		return &storeProvider{store: OpenVault(cfg.File, password)}, nil
+	case "command":
+		cache, _ := time.ParseDuration(cfg.Cache)
+		return &commandProvider{command: cfg.Command, cache: cache}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", cfg.Type)
+}
+
+// envProvider reads secrets from the environment of gantz
+type envProvider struct{}
+
+func (envProvider) Lookup(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("%s is not set", name)
+	}
+	return value, nil
+}
+
+// fileProvider reads secrets from a file, rereading it when it changes
+type fileProvider struct {
+	path  string
+	parse func([]byte) (map[string]string, error)
+
+	mu      sync.Mutex
+	values  map[string]string
+	modTime time.Time
+}
+
+func (p *fileProvider) Lookup(ctx context.Context, name string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return "", err
+	}
+	if p.values == nil || !info.ModTime().Equal(p.modTime) {
+		data, err := os.ReadFile(p.path)
+		if err != nil {
+			return "", err
+		}
+		values, err := p.parse(data)
+		if err != nil {
+			return "", fmt.Errorf("parse %s: %w", p.path, err)
+		}
+		p.values, p.modTime = values, info.ModTime()
+	}
+	value, ok := p.values[name]
+	if !ok {
+		return "", fmt.Errorf("not found in %s", p.path)
+	}
+	return value, nil
+}
+
+// parseDotenv reads KEY=value lines. Values may be quoted; lines starting
+// with # and an "export " prefix are ignored.
+func parseDotenv(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=value", n)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") && len(value) >= 2:
+			value = value[1 : len(value)-1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
+
+// storeProvider reads secrets from a keyring or vault
+type storeProvider struct {
+	store Store
+}
+
+func (p *storeProvider) Lookup(ctx context.Context, name string) (string, error) {
+	return p.store.Get(name)
+}
+
+// commandProvider runs a command such as "pass show {{name}}" and uses what
+// it prints
+type commandProvider struct {
+	command string
+	cache   time.Duration
+
+	mu     sync.Mutex
+	cached map[string]cachedSecret
+}
+
+type cachedSecret struct {
+	value   string
+	fetched time.Time
+}
+
+func (p *commandProvider) Lookup(ctx context.Context, name string) (string, error) {
+	if p.cache > 0 {
+		p.mu.Lock()
+		c, ok := p.cached[name]
+		p.mu.Unlock()
+		if ok && time.Since(c.fetched) < p.cache {
+			return c.value, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	command := strings.ReplaceAll(p.command, "{{name}}", name)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/c", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %v: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("%s: %v", command, err)
+	}
+	value := strings.TrimRight(string(out), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("%s printed nothing", command)
+	}
+
+	if p.cache > 0 {
+		p.mu.Lock()
+		if p.cached == nil {
+			p.cached = make(map[string]cachedSecret)
+		}
+		p.cached[name] = cachedSecret{value: value, fetched: time.Now()}
+		p.mu.Unlock()
+	}
+	return value, nil
+}
+
+// Store is a secret store that gantz secret can write to
+type Store interface {
+	Path() string
+	Get(name string) (string, error)
+	Set(name, value string) error
+	Delete(name string) error
+	Names() ([]string, error)
+}
+
+// ensureDir creates the directory of a store file
+func ensureDir(path string) error {
+	return os.MkdirAll(filepath.Dir(path), 0700)
+}
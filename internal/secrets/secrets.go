@@ -0,0 +1,219 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+)
+
+// Provider looks up secrets by name
+type Provider interface {
+	Lookup(ctx context.Context, name string) (string, error)
+}
+
+// Resolver replaces secret references with values from their providers
+type Resolver struct {
+	cfg map[string]config.SecretProvider
+
+	mu        sync.Mutex
+	providers map[string]Provider
+}
+
+// NewResolver creates a resolver; providers are opened on first use
+func NewResolver(cfg map[string]config.SecretProvider) *Resolver {
+	return &Resolver{cfg: cfg, providers: make(map[string]Provider)}
+}
+
+func (r *Resolver) provider(name string) (Provider, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.providers[name]; ok {
+		return p, nil
+	}
+	pc, ok := r.cfg[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown secret provider %q", name)
+	}
+	p, err := newProvider(pc)
+	if err != nil {
+		return nil, fmt.Errorf("secret provider %q: %w", name, err)
+	}
+	r.providers[name] = p
+	return p, nil
+}
+
+// Resolve replaces the secret references in s. It also returns the values
+// it substituted, so they can be redacted.
+func (r *Resolver) Resolve(ctx context.Context, s string) (string, []string, error) {
+	refs := config.SecretRefs(s)
+	if len(refs) == 0 {
+		return s, nil, nil
+	}
+	var values []string
+	out := make([]byte, 0, len(s))
+	last := 0
+	for _, ref := range refs {
+		p, err := r.provider(ref.Provider)
+		if err != nil {
+			return "", nil, err
+		}
+		value, err := p.Lookup(ctx, ref.Name)
+		if err != nil {
+			return "", nil, fmt.Errorf("secret %s:%s: %w", ref.Provider, ref.Name, err)
+		}
+		out = append(out, s[last:ref.Start]...)
+		out = append(out, value...)
+		values = append(values, value)
+		last = ref.End
+	}
+	out = append(out, s[last:]...)
+	return string(out), values, nil
+}
+
+// ResolveTool returns a copy of tool with its secret references resolved.
+// ${VAR} references are expanded first, so a value containing $ is never
+// expanded again. Scripts get secrets as $GANTZ_SECRET_* environment
+// variables instead of spliced into their text. It also returns the values,
+// so they can be redacted.
+func (r *Resolver) ResolveTool(ctx context.Context, tool *config.Tool) (*config.Tool, []string, error) {
+	if !config.ToolHasSecrets(tool) {
+		return tool, nil, nil
+	}
+	resolved := *tool
+	resolved.EnvExpanded = true
+	var values []string
+	var firstErr error
+	str := func(s string) string {
+		if firstErr != nil || len(config.SecretRefs(s)) == 0 {
+			return s
+		}
+		out, used, err := r.Resolve(ctx, s)
+		if err != nil {
+			firstErr = err
+			return s
+		}
+		values = append(values, used...)
+		return out
+	}
+	expanded := func(s string) string {
+		return str(config.ExpandEnv(s))
+	}
+	list := func(in []string) []string {
+		if in == nil {
+			return nil
+		}
+		out := make([]string, len(in))
+		for i, s := range in {
+			out[i] = str(s)
+		}
+		return out
+	}
+	dict := func(in map[string]string) map[string]string {
+		if in == nil {
+			return nil
+		}
+		out := make(map[string]string, len(in))
+		for k, v := range in {
+			out[k] = expanded(v)
+		}
+		return out
+	}
+
+	resolved.Script.Command = str(tool.Script.Command)
+	resolved.Script.Args = list(tool.Script.Args)
+	resolved.HTTP.URL = expanded(tool.HTTP.URL)
+	resolved.HTTP.Body = expanded(tool.HTTP.Body)
+	resolved.HTTP.Query = dict(tool.HTTP.Query)
+	resolved.HTTP.Headers = dict(tool.HTTP.Headers)
+	resolved.GraphQL.Endpoint = expanded(tool.GraphQL.Endpoint)
+	resolved.GraphQL.Headers = dict(tool.GraphQL.Headers)
+	resolved.GraphQL.Variables = dict(tool.GraphQL.Variables)
+	resolved.Environment = dict(tool.Environment)
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+
+	shell, used, err := r.shellVariables(ctx, &resolved)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolved.Script.Shell = shell
+	return &resolved, append(values, used...), nil
+}
+
+// shellVariables replaces the secret references in a tool's shell script
+// with variable references and adds the values to its environment, so the
+// shell never parses a secret as code
+func (r *Resolver) shellVariables(ctx context.Context, tool *config.Tool) (string, []string, error) {
+	script := tool.Script.Shell
+	refs := config.SecretRefs(script)
+	if len(refs) == 0 {
+		return script, nil, nil
+	}
+	env := make(map[string]string, len(tool.Environment)+len(refs))
+	for k, v := range tool.Environment {
+		env[k] = v
+	}
+	windows := runtime.GOOS == "windows" && tool.Script.Container == nil && tool.Script.SSH == nil
+	var values []string
+	var b strings.Builder
+	last := 0
+	for _, ref := range refs {
+		value, used, err := r.Resolve(ctx, script[ref.Start:ref.End])
+		if err != nil {
+			return "", nil, err
+		}
+		name := secretVariable(ref)
+		env[name] = value
+		values = append(values, used...)
+
+		b.WriteString(script[last:ref.Start])
+		if windows {
+			b.WriteString("%" + name + "%")
+		} else {
+			b.WriteString("${" + name + "}")
+		}
+		last = ref.End
+	}
+	b.WriteString(script[last:])
+	tool.Environment = env
+	return b.String(), values, nil
+}
+
+// secretVariable names the environment variable a script reads a secret
+// from, e.g. GANTZ_SECRET_VAULT_DB_PASSWORD for ${secret:vault:db/password}
+func secretVariable(ref config.SecretRef) string {
+	name := []byte("GANTZ_SECRET_" + strings.ToUpper(ref.Provider+"_"+ref.Name))
+	for i, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// DefaultDir returns the directory of the default keyring and vault files
+func DefaultDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "gantz")
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,261 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Scrypt parameters for new vaults
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// fileStore keeps secrets in one file, rereading it when it changes
+type fileStore struct {
+	path   string
+	decode func([]byte) (map[string]string, error)
+	encode func(map[string]string) ([]byte, error)
+
+	mu      sync.Mutex
+	values  map[string]string
+	modTime time.Time
+}
+
+// OpenKeyring opens a keyring file; an empty path means keyring.json in
+// the user config directory. Like an OS keyring it holds secrets by name
+// for the current user, in a file only they can read.
+func OpenKeyring(path string) Store {
+	if path == "" {
+		path = filepath.Join(DefaultDir(), "keyring.json")
+	}
+	return &fileStore{path: path, decode: decodeKeyring, encode: encodeKeyring}
+}
+
+// OpenVault opens a vault file encrypted with a password; an empty path
+// means vault.json in the user config directory
+func OpenVault(path, password string) Store {
+	if path == "" {
+		path = filepath.Join(DefaultDir(), "vault.json")
+	}
+	return &fileStore{
+		path:   path,
+		decode: func(data []byte) (map[string]string, error) { return decryptVault(data, password) },
+		encode: func(values map[string]string) ([]byte, error) { return encryptVault(values, password) },
+	}
+}
+
+// Path returns the file the store is kept in
+func (s *fileStore) Path() string {
+	return s.path
+}
+
+// load rereads the file if it changed; callers hold s.mu
+func (s *fileStore) load() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.values, s.modTime = map[string]string{}, time.Time{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.values != nil && info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	values, err := s.decode(data)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", s.path, err)
+	}
+	s.values, s.modTime = values, info.ModTime()
+	return nil
+}
+
+// save writes the values to a temporary file and moves it into place;
+// callers hold s.mu
+func (s *fileStore) save(values map[string]string) error {
+	data, err := s.encode(values)
+	if err != nil {
+		return err
+	}
+	if err := ensureDir(s.path); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.values = nil // Reread on next use
+	return nil
+}
+
+// Get returns a secret
+func (s *fileStore) Get(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	value, ok := s.values[name]
+	if !ok {
+		return "", fmt.Errorf("not found in %s", s.path)
+	}
+	return value, nil
+}
+
+// Set adds or replaces a secret
+func (s *fileStore) Set(name, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	values := make(map[string]string, len(s.values)+1)
+	for k, v := range s.values {
+		values[k] = v
+	}
+	values[name] = value
+	return s.save(values)
+}
+
+// Delete removes a secret
+func (s *fileStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.values[name]; !ok {
+		return fmt.Errorf("no secret %q in %s", name, s.path)
+	}
+	values := make(map[string]string, len(s.values))
+	for k, v := range s.values {
+		if k != name {
+			values[k] = v
+		}
+	}
+	return s.save(values)
+}
+
+// Names returns the names of the stored secrets in order
+func (s *fileStore) Names() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return sortedKeys(s.values), nil
+}
+
+type keyringFile struct {
+	Secrets map[string]string `json:"secrets"`
+}
+
+func decodeKeyring(data []byte) (map[string]string, error) {
+	var f keyringFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Secrets == nil {
+		f.Secrets = map[string]string{}
+	}
+	return f.Secrets, nil
+}
+
+func encodeKeyring(values map[string]string) ([]byte, error) {
+	return json.MarshalIndent(keyringFile{Secrets: values}, "", "  ")
+}
+
+// vaultFile is an AES-256-GCM encrypted JSON object of secrets, keyed with
+// scrypt from a password
+type vaultFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func vaultCipher(password string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(password), salt, n, r, p, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptVault(values map[string]string, password string) ([]byte, error) {
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	f := vaultFile{Version: 1, KDF: "scrypt", N: scryptN, R: scryptR, P: scryptP, Salt: make([]byte, 16)}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := vaultCipher(password, f.Salt, f.N, f.R, f.P)
+	if err != nil {
+		return nil, err
+	}
+	f.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return nil, err
+	}
+	f.Ciphertext = aead.Seal(nil, f.Nonce, plaintext, nil)
+	return json.MarshalIndent(f, "", "  ")
+}
+
+func decryptVault(data []byte, password string) (map[string]string, error) {
+	var f vaultFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != 1 || f.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported vault format")
+	}
+	aead, err := vaultCipher(password, f.Salt, f.N, f.R, f.P)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("corrupt vault")
+	}
+	plaintext, err := aead.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("wrong vault password or corrupt vault")
+	}
+	var values map[string]string
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = map[string]string{}
+	}
+	return values, nil
+}
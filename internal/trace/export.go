@@ -0,0 +1,249 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Export settings
+const (
+	batchSize     = 256
+	queueSize     = 4096
+	flushInterval = 5 * time.Second
+)
+
+// exporter sends finished spans in batches to an OTLP/HTTP collector
+type exporter struct {
+	endpoint string
+	resource []attribute
+	client   *http.Client
+
+	queue   chan *Span
+	stopped chan struct{}
+	done    chan struct{}
+
+	dropped  atomic.Int64
+	failedAt time.Time // Last reported export failure
+}
+
+var active atomic.Pointer[exporter]
+
+func current() *exporter {
+	return active.Load()
+}
+
+// Setup starts exporting spans to endpoint, the OTLP/HTTP traces URL, and
+// returns a function that flushes and stops the export. An empty endpoint
+// turns tracing off.
+func Setup(endpoint, serviceName, version string) (shutdown func()) {
+	if endpoint == "" {
+		active.Store(nil)
+		return func() {}
+	}
+	e := &exporter{
+		endpoint: endpoint,
+		resource: []attribute{
+			stringAttr("service.name", serviceName),
+			stringAttr("service.version", version),
+		},
+		client:  &http.Client{Timeout: 10 * time.Second},
+		queue:   make(chan *Span, queueSize),
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go e.run()
+	active.Store(e)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			active.CompareAndSwap(e, nil)
+			close(e.done)
+			<-e.stopped // Wait for the last batch
+		})
+	}
+}
+
+func (e *exporter) enqueue(s *Span) {
+	select {
+	case e.queue <- s:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+func (e *exporter) run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	send := func() {
+		if len(batch) > 0 {
+			e.export(batch)
+			batch = nil
+		}
+	}
+	for {
+		select {
+		case s := <-e.queue:
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case <-e.done:
+			for {
+				select {
+				case s := <-e.queue:
+					batch = append(batch, s)
+				default:
+					send()
+					close(e.stopped)
+					return
+				}
+			}
+		}
+	}
+}
+
+// export posts spans to the collector; failures are reported at most once
+// a minute so a missing collector doesn't flood the terminal
+func (e *exporter) export(spans []*Span) {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			err = fmt.Errorf("collector returned %s", resp.Status)
+		}
+	}
+	if dropped := e.dropped.Swap(0); dropped > 0 && err == nil {
+		err = fmt.Errorf("dropped %d spans, queue full", dropped)
+	}
+	if err != nil && time.Since(e.failedAt) > time.Minute {
+		e.failedAt = time.Now()
+		slog.Warn("Trace export failed", "endpoint", e.endpoint, "error", err)
+	}
+}
+
+// OTLP/JSON encoding, see opentelemetry-proto's trace_service.proto
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanJSON `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanJSON struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              Kind        `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []attribute `json:"attributes,omitempty"`
+	Status            status      `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code"` // 0 unset, 1 ok, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+type attribute struct {
+	Key   string    `json:"key"`
+	Value attrValue `json:"value"`
+}
+
+type attrValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"` // int64 as a string in OTLP/JSON
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func stringAttr(key, value string) attribute {
+	return attribute{Key: key, Value: attrValue{StringValue: &value}}
+}
+
+func toAttribute(key string, value interface{}) attribute {
+	switch v := value.(type) {
+	case string:
+		return stringAttr(key, v)
+	case bool:
+		return attribute{Key: key, Value: attrValue{BoolValue: &v}}
+	case int:
+		s := fmt.Sprint(v)
+		return attribute{Key: key, Value: attrValue{IntValue: &s}}
+	case int64:
+		s := fmt.Sprint(v)
+		return attribute{Key: key, Value: attrValue{IntValue: &s}}
+	case float64:
+		return attribute{Key: key, Value: attrValue{DoubleValue: &v}}
+	}
+	return stringAttr(key, fmt.Sprint(value))
+}
+
+func (e *exporter) request(spans []*Span) exportRequest {
+	list := make([]spanJSON, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		span := spanJSON{
+			TraceID:           hex.EncodeToString(s.sc.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.sc.SpanID[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: fmt.Sprint(s.start.UnixNano()),
+			EndTimeUnixNano:   fmt.Sprint(s.end.UnixNano()),
+		}
+		if s.parent != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parent[:])
+		}
+		keys := make([]string, 0, len(s.attrs))
+		for k := range s.attrs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			span.Attributes = append(span.Attributes, toAttribute(k, s.attrs[k]))
+		}
+		if s.failed {
+			span.Status = status{Code: 2, Message: s.message}
+		}
+		s.mu.Unlock()
+		list = append(list, span)
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: e.resource},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: "gantz"}, Spans: list}},
+	}}}
+}
@@ -0,0 +1,204 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind says what side of a call a span is on
+type Kind int
+
+// Span kinds, numbered as in OTLP
+const (
+	Internal Kind = 1
+	Server   Kind = 2
+	Client   Kind = 3
+)
+
+// SpanContext identifies a span across processes
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// Parse reads a W3C traceparent header such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func Parse(traceparent string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	traceID, err1 := hex.DecodeString(parts[1])
+	spanID, err2 := hex.DecodeString(parts[2])
+	flags, err3 := hex.DecodeString(parts[3])
+	if err1 != nil || err2 != nil || err3 != nil || len(traceID) != 16 || len(spanID) != 8 || len(flags) != 1 {
+		return sc, false
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.valid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+func (sc SpanContext) valid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// String formats the span context as a traceparent header
+func (sc SpanContext) String() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%x-%x-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// Span is a timed operation in a trace. A nil *Span is valid and does nothing,
+// which is what Start returns while tracing is off.
+type Span struct {
+	sc     SpanContext
+	parent [8]byte
+	kind   Kind
+	start  time.Time
+
+	mu      sync.Mutex
+	name    string
+	end     time.Time
+	attrs   map[string]interface{}
+	failed  bool
+	message string
+	ended   bool
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// WithRemoteParent makes the span in a traceparent header the parent of
+// spans started from ctx, unless ctx already has a span. Invalid headers
+// are ignored.
+func WithRemoteParent(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	if _, ok := currentContext(ctx); ok {
+		return ctx
+	}
+	sc, ok := Parse(traceparent)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// FromContext returns the span started for ctx, or nil
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// currentContext returns the span context of the span in ctx, or of the
+// remote parent
+func currentContext(ctx context.Context) (SpanContext, bool) {
+	if span := FromContext(ctx); span != nil {
+		return span.sc, true
+	}
+	if sc, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		return sc, true
+	}
+	return SpanContext{}, false
+}
+
+// Traceparent returns the traceparent header to send with requests made
+// from ctx, or "" if ctx is not part of a trace
+func Traceparent(ctx context.Context) string {
+	if sc, ok := currentContext(ctx); ok {
+		return sc.String()
+	}
+	return ""
+}
+
+// Start begins a span as a child of the span or remote parent in ctx. It
+// returns nil if tracing is off.
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	if current() == nil {
+		return ctx, nil
+	}
+	span := &Span{name: name, kind: kind, start: time.Now()}
+	if parent, ok := currentContext(ctx); ok {
+		span.sc.TraceID = parent.TraceID
+		span.sc.Sampled = parent.Sampled
+		span.parent = parent.SpanID
+	} else {
+		rand.Read(span.sc.TraceID[:])
+		span.sc.Sampled = true
+	}
+	rand.Read(span.sc.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetName renames the span
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetAttr sets an attribute; values are strings, bools, ints or floats
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.attrs == nil {
+		s.attrs = make(map[string]interface{})
+	}
+	s.attrs[key] = value
+	s.mu.Unlock()
+}
+
+// Fail marks the span as failed
+func (s *Span) Fail(message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.failed = true
+	s.message = message
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	if s.sc.Sampled {
+		if e := current(); e != nil {
+			e.enqueue(s)
+		}
+	}
+}
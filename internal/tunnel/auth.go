@@ -0,0 +1,35 @@
+package tunnel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AuthError is returned by MCPHandler.Authenticate to reject a request
+// before it is handled
+type AuthError struct {
+	Status           int    // HTTP status for the client
+	Code             string // RFC 6750 error code; empty when no credentials were sent
+	Message          string
+	Scope            string // Scopes needed, for insufficient_scope errors
+	ResourceMetadata string // URL of the OAuth protected resource metadata
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+// Challenge returns the WWW-Authenticate header for the response
+func (e *AuthError) Challenge() string {
+	params := []string{`realm="gantz"`}
+	if e.ResourceMetadata != "" {
+		params = append(params, fmt.Sprintf("resource_metadata=%q", e.ResourceMetadata))
+	}
+	if e.Code != "" {
+		params = append(params, fmt.Sprintf("error=%q", e.Code), fmt.Sprintf("error_description=%q", e.Message))
+	}
+	if e.Scope != "" {
+		params = append(params, fmt.Sprintf("scope=%q", e.Scope))
+	}
+	return "Bearer " + strings.Join(params, ", ")
+}
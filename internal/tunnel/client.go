@@ -1,18 +1,29 @@
 package tunnel
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/gantz-ai/gantz-cli/internal/metrics"
+	"github.com/gantz-ai/gantz-cli/internal/trace"
 )
 
-// MCPHandler handles MCP requests
+// MCPHandler authenticates and handles MCP requests, client responses to
+// requests the server sent through a Peer, and the end of client sessions
 type MCPHandler interface {
-	HandleRequest(req *MCPRequest) (*MCPResponse, error)
+	// Authenticate checks the Authorization header a client sent and returns
+	// the context to handle its messages with, or an *AuthError
+	Authenticate(ctx context.Context, authorization string) (context.Context, error)
+	HandleRequest(ctx context.Context, req *MCPRequest) (*MCPResponse, error)
+	HandleResponse(ctx context.Context, resp *MCPResponse)
+	CloseSession(id string)
 }
 
 // MCPRequest represents an incoming MCP request
@@ -26,11 +37,18 @@ type MCPRequest struct {
 // MCPResponse represents an MCP response
 type MCPResponse struct {
 	JSONRPC string      `json:"jsonrpc"`
-	ID      interface{} `json:"id,omitempty"`
+	ID      interface{} `json:"id"`
 	Result  interface{} `json:"result,omitempty"`
 	Error   *MCPError   `json:"error,omitempty"`
 }
 
+// MCPNotification represents a server-initiated MCP notification
+type MCPNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
 // MCPError represents an MCP error
 type MCPError struct {
 	Code    int         `json:"code"`
@@ -53,8 +71,14 @@ type Client struct {
 	toolCount         int
 	authToken         string
 	onClientConnected ClientConnectedCallback
+	connects          int           // Successful connections so far
+	inflight          chan struct{} // Slots for relayed requests being handled
 }
 
+// maxInflightRequests is how many relayed requests are handled at once;
+// further requests are turned away until one finishes
+const maxInflightRequests = 64
+
 // NewClient creates a new tunnel client
 func NewClient(relayURL string, handler MCPHandler, version string, toolCount int, authToken string) *Client {
 	return &Client{
@@ -64,6 +88,7 @@ func NewClient(relayURL string, handler MCPHandler, version string, toolCount in
 		version:   version,
 		toolCount: toolCount,
 		authToken: authToken,
+		inflight:  make(chan struct{}, maxInflightRequests),
 	}
 }
 
@@ -86,6 +111,12 @@ type TunnelMessage struct {
 	Payload   json.RawMessage `json:"payload,omitempty"`
 	Error     string          `json:"error,omitempty"`
 	ClientIP  string          `json:"client_ip,omitempty"`
+	SessionID string          `json:"session_id,omitempty"` // MCP session (the client's Mcp-Session-Id)
+
+	Authorization string            `json:"authorization,omitempty"` // Client's Authorization header
+	Traceparent   string            `json:"traceparent,omitempty"`   // Client's traceparent header
+	Status        int               `json:"status,omitempty"`        // HTTP status for a rejected request
+	Headers       map[string]string `json:"headers,omitempty"`       // Extra HTTP headers for the client
 }
 
 // Connect establishes a tunnel connection and returns the public URL
@@ -100,6 +131,7 @@ func (c *Client) Connect() (string, error) {
 
 	conn, resp, err := websocket.DefaultDialer.Dial(c.relayURL+"/tunnel", header)
 	if err != nil {
+		metrics.TunnelConnects.With("error").Inc()
 		// Check if it's a version rejection (HTTP 426 Upgrade Required)
 		if resp != nil && resp.StatusCode == http.StatusUpgradeRequired {
 			return "", fmt.Errorf("version outdated - run: gantz update")
@@ -113,15 +145,23 @@ func (c *Client) Connect() (string, error) {
 	var msg TunnelMessage
 	if err := conn.ReadJSON(&msg); err != nil {
 		conn.Close()
+		metrics.TunnelConnects.With("error").Inc()
 		return "", fmt.Errorf("read registration: %w", err)
 	}
 
 	if msg.Type != "registered" {
 		conn.Close()
+		metrics.TunnelConnects.With("error").Inc()
 		return "", fmt.Errorf("unexpected message type: %s", msg.Type)
 	}
 
 	c.tunnelURL = msg.TunnelURL
+	metrics.TunnelConnects.With("ok").Inc()
+	if c.connects > 0 {
+		metrics.TunnelReconnects.Inc()
+	}
+	c.connects++
+	metrics.TunnelConnected.Set(1)
 
 	// Start message handler
 	go c.handleMessages()
@@ -134,6 +174,7 @@ func (c *Client) Connect() (string, error) {
 
 func (c *Client) handleMessages() {
 	defer close(c.done)
+	defer metrics.TunnelConnected.Set(0)
 
 	for {
 		var msg TunnelMessage
@@ -141,61 +182,125 @@ func (c *Client) handleMessages() {
 			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
 				return
 			}
-			fmt.Printf("Error reading message: %v\n", err)
+			slog.Error("Tunnel read failed", "error", err)
 			return
 		}
 
 		switch msg.Type {
 		case "request":
-			go c.handleRequest(msg)
+			// Responses to our own requests are answered at once so that
+			// tool calls waiting on them can finish, even when every slot
+			// is taken
+			if IsResponse(msg.Payload) {
+				go c.handleRequest(msg)
+				continue
+			}
+			select {
+			case c.inflight <- struct{}{}:
+				go func(msg TunnelMessage) {
+					defer func() { <-c.inflight }()
+					c.handleRequest(msg)
+				}(msg)
+			default:
+				c.sendBusy(msg.RequestID)
+			}
 		case "ping":
 			c.sendPong()
 		case "client_connected":
 			if c.onClientConnected != nil && msg.ClientIP != "" {
 				c.onClientConnected(msg.ClientIP)
 			}
+		case "session_closed":
+			if msg.SessionID != "" {
+				c.handler.CloseSession(msg.SessionID)
+			}
 		}
 	}
 }
 
 func (c *Client) handleRequest(msg TunnelMessage) {
-	var req MCPRequest
-	if err := json.Unmarshal(msg.Payload, &req); err != nil {
-		c.sendError(msg.RequestID, -32700, "Parse error")
-		return
+	// The relay request is the root of the trace unless the client sent a
+	// traceparent header or one in the request's _meta
+	method, traceparent := payloadTrace(msg.Payload)
+	if msg.Traceparent != "" {
+		traceparent = msg.Traceparent
+	}
+	ctx, span := trace.Start(trace.WithRemoteParent(context.Background(), traceparent), "relay request", trace.Server)
+	defer span.End()
+	span.SetAttr("gantz.request_id", msg.RequestID)
+	if method != "" {
+		span.SetAttr("rpc.method", method)
+	}
+	if msg.ClientIP != "" {
+		span.SetAttr("client.address", msg.ClientIP)
 	}
 
-	resp, err := c.handler.HandleRequest(&req)
+	ctx, err := c.handler.Authenticate(ctx, msg.Authorization)
 	if err != nil {
-		c.sendError(msg.RequestID, -32603, err.Error())
+		metrics.TunnelRequests.With("rejected").Inc()
+		span.Fail("rejected")
+		c.sendRejection(msg.RequestID, err)
 		return
 	}
+	metrics.TunnelRequests.With("ok").Inc()
 
-	c.sendResponse(msg.RequestID, resp)
-}
-
-func (c *Client) sendResponse(requestID string, resp *MCPResponse) {
-	payload, _ := json.Marshal(resp)
+	ref := &SessionRef{ID: msg.SessionID}
+	ref.Notifier = &relayNotifier{client: c, ref: ref}
+	ctx = WithSession(ctx, ref)
+	if msg.ClientIP != "" {
+		ctx = WithClientIP(ctx, msg.ClientIP)
+	}
+	ctx = WithPeer(ctx, &relayPeer{client: c, requestID: msg.RequestID})
+	reply := HandlePayload(ctx, c.handler, msg.Payload)
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	// An empty response tells the relay the message was accepted without a
+	// reply. The session ID is returned to the client as Mcp-Session-Id.
 	c.conn.WriteJSON(TunnelMessage{
 		Type:      "response",
-		RequestID: requestID,
-		Payload:   payload,
+		RequestID: msg.RequestID,
+		SessionID: ref.ID,
+		Payload:   reply,
 	})
 }
 
-func (c *Client) sendError(requestID string, code int, message string) {
-	resp := &MCPResponse{
-		JSONRPC: "2.0",
-		Error: &MCPError{
-			Code:    code,
-			Message: message,
-		},
+// sendRejection answers a request that failed authentication with an HTTP
+// error instead of a JSON-RPC response
+func (c *Client) sendRejection(requestID string, err error) {
+	slog.Warn("Rejected request", "request_id", requestID, "error", err)
+	rejection := TunnelMessage{
+		Type:      "response",
+		RequestID: requestID,
+		Status:    http.StatusUnauthorized,
+		Error:     err.Error(),
+	}
+	if authErr, ok := err.(*AuthError); ok {
+		rejection.Status = authErr.Status
+		rejection.Headers = map[string]string{"WWW-Authenticate": authErr.Challenge()}
 	}
-	c.sendResponse(requestID, resp)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.WriteJSON(rejection)
+}
+
+// sendBusy turns a request away with an HTTP 503 while too many relayed
+// requests are in flight
+func (c *Client) sendBusy(requestID string) {
+	metrics.TunnelRequests.With("busy").Inc()
+	slog.Warn("Too many relayed requests in flight", "request_id", requestID, "limit", maxInflightRequests)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.WriteJSON(TunnelMessage{
+		Type:      "response",
+		RequestID: requestID,
+		Status:    http.StatusServiceUnavailable,
+		Error:     "too many requests in flight",
+		Headers:   map[string]string{"Retry-After": "1"},
+	})
 }
 
 func (c *Client) sendPong() {
@@ -0,0 +1,200 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// JSON-RPC error codes used by the transport
+const (
+	parseError     = -32700
+	invalidRequest = -32600
+	internalError  = -32603
+)
+
+// maxBatchSize is the most messages a batch may hold; larger batches are
+// rejected before anything in them runs
+const maxBatchSize = 100
+
+// maxBatchConcurrency is how many messages of a batch are handled at once
+const maxBatchConcurrency = 8
+
+// rpcMessage is used to tell requests from responses on the wire
+type rpcMessage struct {
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *MCPError       `json:"error"`
+}
+
+// IsResponse reports whether a JSON-RPC payload is a response rather than a
+// request or notification
+func IsResponse(payload []byte) bool {
+	var msg rpcMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return false
+	}
+	return msg.Method == "" && (msg.Result != nil || msg.Error != nil)
+}
+
+// traceMessage picks the trace context out of a request's params
+type traceMessage struct {
+	Method string `json:"method"`
+	Params struct {
+		Meta struct {
+			Traceparent string `json:"traceparent"`
+		} `json:"_meta"`
+	} `json:"params"`
+}
+
+// MetaTraceparent returns the W3C traceparent a client sent in the _meta of
+// a request's params, if any
+func MetaTraceparent(params json.RawMessage) string {
+	var msg traceMessage
+	if len(params) == 0 || json.Unmarshal([]byte(`{"params":`+string(params)+`}`), &msg) != nil {
+		return ""
+	}
+	return msg.Params.Meta.Traceparent
+}
+
+// payloadTrace returns the method and traceparent of a single message; a
+// batch has neither
+func payloadTrace(payload []byte) (method, traceparent string) {
+	var msg traceMessage
+	if json.Unmarshal(payload, &msg) != nil {
+		return "", ""
+	}
+	return msg.Method, msg.Params.Meta.Traceparent
+}
+
+// HandlePayload processes a JSON-RPC message or batch from a client and
+// returns the encoded reply. It returns nil when nothing is to be sent back,
+// as for notifications and responses to server-initiated requests.
+func HandlePayload(ctx context.Context, h MCPHandler, payload []byte) []byte {
+	payload = bytes.TrimSpace(payload)
+	if len(payload) > 0 && payload[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(payload, &batch); err != nil {
+			return encode(errorResponse(nil, parseError, "Parse error"))
+		}
+		if len(batch) == 0 {
+			return encode(errorResponse(nil, invalidRequest, "Invalid Request: empty batch"))
+		}
+		if len(batch) > maxBatchSize {
+			return encode(errorResponse(nil, invalidRequest, fmt.Sprintf("Invalid Request: batch of %d messages exceeds the limit of %d", len(batch), maxBatchSize)))
+		}
+
+		replies := make([]*MCPResponse, len(batch))
+		if order := initializeFirst(batch); order != nil {
+			// Nothing else may run before the session is initialized, so
+			// initialize goes first and the rest follows in order
+			for _, i := range order {
+				replies[i] = handleMessage(ctx, h, batch[i])
+			}
+		} else {
+			// Otherwise entries are independent, so run a few at a time
+			sem := make(chan struct{}, maxBatchConcurrency)
+			var wg sync.WaitGroup
+			for i, msg := range batch {
+				wg.Add(1)
+				sem <- struct{}{}
+				go func(i int, msg json.RawMessage) {
+					defer func() {
+						<-sem
+						wg.Done()
+					}()
+					replies[i] = handleMessage(ctx, h, msg)
+				}(i, msg)
+			}
+			wg.Wait()
+		}
+
+		var out []*MCPResponse
+		for _, reply := range replies {
+			if reply != nil {
+				out = append(out, reply)
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return encode(out)
+	}
+
+	if !json.Valid(payload) {
+		return encode(errorResponse(nil, parseError, "Parse error"))
+	}
+	if reply := handleMessage(ctx, h, payload); reply != nil {
+		return encode(reply)
+	}
+	return nil
+}
+
+// initializeFirst returns the order to handle a batch containing initialize
+// in: initialize requests first, then the other messages as sent. It returns
+// nil for batches without initialize.
+func initializeFirst(batch []json.RawMessage) []int {
+	var first, rest []int
+	for i, msg := range batch {
+		if method, _ := payloadTrace(msg); method == "initialize" {
+			first = append(first, i)
+		} else {
+			rest = append(rest, i)
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	return append(first, rest...)
+}
+
+// handleMessage processes one JSON-RPC message, returning nil if it gets no
+// response
+func handleMessage(ctx context.Context, h MCPHandler, msg json.RawMessage) *MCPResponse {
+	if IsResponse(msg) {
+		var resp MCPResponse
+		if err := json.Unmarshal(msg, &resp); err != nil {
+			return errorResponse(nil, invalidRequest, "Invalid Request")
+		}
+		h.HandleResponse(ctx, &resp)
+		return nil
+	}
+
+	var req MCPRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return errorResponse(nil, invalidRequest, "Invalid Request")
+	}
+	if req.JSONRPC != "2.0" {
+		return errorResponse(req.ID, invalidRequest, `Invalid Request: jsonrpc must be "2.0"`)
+	}
+	if req.Method == "" {
+		return errorResponse(req.ID, invalidRequest, "Invalid Request: missing method")
+	}
+
+	resp, err := h.HandleRequest(ctx, &req)
+	if req.ID == nil {
+		return nil // Notifications are never answered
+	}
+	if err != nil {
+		return errorResponse(req.ID, internalError, err.Error())
+	}
+	return resp
+}
+
+func errorResponse(id interface{}, code int, message string) *MCPResponse {
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: &MCPError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+func encode(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
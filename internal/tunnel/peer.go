@@ -0,0 +1,113 @@
+package tunnel
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Peer is the client a request came from. It can receive requests initiated
+// by the server, such as sampling/createMessage or elicitation/create; the
+// client's answer comes back through MCPHandler.HandleResponse.
+type Peer interface {
+	SendRequest(req *MCPRequest) error
+}
+
+// Notifier delivers server-initiated notifications to a client
+type Notifier interface {
+	Notify(n *MCPNotification) error
+}
+
+// SessionRef identifies the MCP session a message belongs to. Transports set
+// ID from what the client sent, and read it back after handling initialize,
+// which assigns an ID to a new session. Notifier, if the transport can push
+// messages, delivers notifications to the session's client.
+type SessionRef struct {
+	ID       string
+	Notifier Notifier
+}
+
+type peerKey struct{}
+
+type sessionKey struct{}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a context carrying the address a request came from
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIPFromContext returns the address a request came from, or ""
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// WithSession returns a context carrying the session of a message
+func WithSession(ctx context.Context, ref *SessionRef) context.Context {
+	return context.WithValue(ctx, sessionKey{}, ref)
+}
+
+// SessionFromContext returns the session of a message, or nil if the
+// transport does not track sessions
+func SessionFromContext(ctx context.Context) *SessionRef {
+	ref, _ := ctx.Value(sessionKey{}).(*SessionRef)
+	return ref
+}
+
+// WithPeer returns a context carrying the client that sent a request
+func WithPeer(ctx context.Context, p Peer) context.Context {
+	return context.WithValue(ctx, peerKey{}, p)
+}
+
+// PeerFromContext returns the client that sent a request, or nil
+func PeerFromContext(ctx context.Context) Peer {
+	p, _ := ctx.Value(peerKey{}).(Peer)
+	return p
+}
+
+// relayPeer sends server-initiated requests through the relay to the client
+// whose request is identified by requestID
+type relayPeer struct {
+	client    *Client
+	requestID string
+}
+
+func (p *relayPeer) SendRequest(req *MCPRequest) error {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	p.client.mu.Lock()
+	defer p.client.mu.Unlock()
+
+	return p.client.conn.WriteJSON(TunnelMessage{
+		Type:      "server_request",
+		RequestID: p.requestID,
+		Payload:   payload,
+	})
+}
+
+// relayNotifier sends notifications through the relay to the clients of one
+// session
+type relayNotifier struct {
+	client *Client
+	ref    *SessionRef
+}
+
+func (n *relayNotifier) Notify(msg *MCPNotification) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	n.client.mu.Lock()
+	defer n.client.mu.Unlock()
+
+	return n.client.conn.WriteJSON(TunnelMessage{
+		Type:      "notification",
+		SessionID: n.ref.ID,
+		Payload:   payload,
+	})
+}
@@ -0,0 +1,271 @@
+package upstream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+)
+
+// httpConn talks to an upstream server over HTTP. In "http" mode every
+// request is POSTed to the URL and answered in the response body (JSON or an
+// SSE stream). In "sse" mode a long-lived GET stream announces the endpoint
+// to POST to and carries responses and notifications.
+type httpConn struct {
+	cfg      config.Upstream
+	client   *http.Client
+	postURL  string
+	pending  *pendingCalls
+	onNotify notificationHandler
+	closed   chan struct{}
+	once     sync.Once
+	cancel   context.CancelFunc
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func dialHTTP(cfg config.Upstream, onNotify notificationHandler) (*httpConn, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &httpConn{
+		cfg:      cfg,
+		client:   &http.Client{},
+		postURL:  cfg.URL,
+		pending:  newPendingCalls(),
+		onNotify: onNotify,
+		closed:   make(chan struct{}),
+		cancel:   cancel,
+	}
+
+	if !isSSETransport(cfg) {
+		return c, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.URL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.setHeaders(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("GET %s: HTTP %d", cfg.URL, resp.StatusCode)
+	}
+
+	endpoint := make(chan string, 1)
+	go func() {
+		defer resp.Body.Close()
+		defer c.close()
+		readSSE(resp.Body, func(event, data string) {
+			if event == "endpoint" {
+				select {
+				case endpoint <- data:
+				default:
+				}
+				return
+			}
+			c.handleMessage([]byte(data))
+		})
+	}()
+
+	select {
+	case ep := <-endpoint:
+		base, err := url.Parse(cfg.URL)
+		if err != nil {
+			c.close()
+			return nil, err
+		}
+		ref, err := url.Parse(ep)
+		if err != nil {
+			c.close()
+			return nil, fmt.Errorf("invalid endpoint event: %w", err)
+		}
+		c.postURL = base.ResolveReference(ref).String()
+	case <-c.closed:
+		return nil, fmt.Errorf("SSE stream closed before endpoint event")
+	case <-time.After(10 * time.Second):
+		c.close()
+		return nil, fmt.Errorf("no endpoint event from %s", cfg.URL)
+	}
+
+	return c, nil
+}
+
+// isSSETransport reports whether an upstream uses the legacy HTTP+SSE transport
+func isSSETransport(cfg config.Upstream) bool {
+	if cfg.Transport != "" {
+		return cfg.Transport == "sse"
+	}
+	u, err := url.Parse(cfg.URL)
+	return err == nil && strings.HasSuffix(u.Path, "/sse")
+}
+
+func (c *httpConn) setHeaders(req *http.Request) {
+	for k, v := range c.cfg.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	c.mu.Lock()
+	if c.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", c.sessionID)
+	}
+	c.mu.Unlock()
+}
+
+func (c *httpConn) handleMessage(data []byte) {
+	var msg rpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+	switch {
+	case msg.isResponse():
+		c.pending.deliver(&msg)
+	case msg.Method != "" && len(msg.ID) > 0:
+		go c.post(context.Background(), replyToRequest(&msg))
+	case msg.Method != "":
+		c.onNotify(msg.Method, msg.Params)
+	}
+}
+
+// post sends a message and dispatches any messages returned in the response body
+func (c *httpConn) post(ctx context.Context, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.postURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	c.setHeaders(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// Unless the caller gave up, the server is unreachable; dropping the
+		// connection makes the supervisor reconnect
+		if ctx.Err() == nil {
+			c.close()
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		c.mu.Lock()
+		c.sessionID = id
+		c.mu.Unlock()
+	}
+
+	// The server forgot our session (e.g. it restarted); a new connection
+	// initializes a new one
+	if resp.StatusCode == http.StatusNotFound && (req.Header.Get("Mcp-Session-Id") != "" || isSSETransport(c.cfg)) {
+		c.close()
+	}
+
+	if resp.StatusCode >= 400 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(text)))
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		readSSE(resp.Body, func(event, data string) {
+			c.handleMessage([]byte(data))
+		})
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil // Accepted; the response arrives on the SSE stream
+	}
+	if data[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(data, &batch); err == nil {
+			for _, item := range batch {
+				c.handleMessage(item)
+			}
+		}
+		return nil
+	}
+	c.handleMessage(data)
+	return nil
+}
+
+func (c *httpConn) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	id, ch := c.pending.add()
+	if err := c.post(ctx, newRequest(id, method, params)); err != nil {
+		c.pending.remove(id)
+		return nil, err
+	}
+	return c.pending.wait(ctx, id, ch, c.closed)
+}
+
+func (c *httpConn) notify(method string, params interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return c.post(ctx, newNotification(method, params))
+}
+
+func (c *httpConn) done() <-chan struct{} {
+	return c.closed
+}
+
+func (c *httpConn) close() error {
+	c.once.Do(func() {
+		c.cancel()
+		close(c.closed)
+	})
+	return nil
+}
+
+// readSSE calls fn for every event in a server-sent events stream
+func readSSE(r io.Reader, fn func(event, data string)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	event := ""
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if event == "" {
+					event = "message"
+				}
+				fn(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// Comment / keepalive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package upstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+)
+
+// stdioConn talks to an upstream server spawned as a child process, using
+// newline-delimited JSON-RPC over its stdin and stdout
+type stdioConn struct {
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	pending  *pendingCalls
+	onNotify notificationHandler
+	writeMu  sync.Mutex
+	closed   chan struct{}
+
+	mu         sync.Mutex
+	lastStderr string
+}
+
+func dialStdio(cfg config.Upstream, onNotify notificationHandler) (*stdioConn, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	if cfg.WorkingDir != "" {
+		cmd.Dir = os.ExpandEnv(cfg.WorkingDir)
+	}
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Environment {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, os.ExpandEnv(v)))
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start '%s': %w", cfg.Command, err)
+	}
+
+	c := &stdioConn{
+		cmd:      cmd,
+		stdin:    stdin,
+		pending:  newPendingCalls(),
+		onNotify: onNotify,
+		closed:   make(chan struct{}),
+	}
+
+	go c.readStderr(stderr)
+	go c.readLoop(stdout)
+
+	return c, nil
+}
+
+func (c *stdioConn) readLoop(stdout io.Reader) {
+	defer func() {
+		c.cmd.Wait()
+		close(c.closed)
+	}()
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue // Not JSON-RPC (stray log output)
+		}
+		switch {
+		case msg.isResponse():
+			c.pending.deliver(&msg)
+		case msg.Method != "" && len(msg.ID) > 0:
+			c.write(replyToRequest(&msg))
+		case msg.Method != "":
+			c.onNotify(msg.Method, msg.Params)
+		}
+	}
+}
+
+// readStderr keeps the last line the server logged, to explain crashes
+func (c *stdioConn) readStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			c.mu.Lock()
+			c.lastStderr = line
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (c *stdioConn) write(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (c *stdioConn) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	id, ch := c.pending.add()
+	if err := c.write(newRequest(id, method, params)); err != nil {
+		c.pending.remove(id)
+		return nil, c.describe(err)
+	}
+	result, err := c.pending.wait(ctx, id, ch, c.closed)
+	if err != nil {
+		return nil, c.describe(err)
+	}
+	return result, nil
+}
+
+func (c *stdioConn) notify(method string, params interface{}) error {
+	return c.describe(c.write(newNotification(method, params)))
+}
+
+// describe adds the server's last stderr line to errors caused by it exiting
+func (c *stdioConn) describe(err error) error {
+	if err == nil {
+		return nil
+	}
+	select {
+	case <-c.closed:
+	default:
+		return err
+	}
+	c.mu.Lock()
+	last := c.lastStderr
+	c.mu.Unlock()
+	if last == "" {
+		return fmt.Errorf("process exited: %v", c.cmd.ProcessState)
+	}
+	return fmt.Errorf("process exited: %v: %s", c.cmd.ProcessState, last)
+}
+
+func (c *stdioConn) done() <-chan struct{} {
+	return c.closed
+}
+
+func (c *stdioConn) close() error {
+	c.stdin.Close()
+	select {
+	case <-c.closed:
+		return nil
+	default:
+	}
+	if c.cmd.Process != nil {
+		return c.cmd.Process.Kill()
+	}
+	return nil
+}
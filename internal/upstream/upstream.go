@@ -0,0 +1,504 @@
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gantz-ai/gantz-cli/internal/config"
+)
+
+// protocolVersion is the MCP version gantz requests from upstream servers
+const protocolVersion = "2024-11-05"
+
+// conn is a JSON-RPC connection to an upstream MCP server
+type conn interface {
+	// call sends a request and waits for its result
+	call(ctx context.Context, method string, params interface{}) (json.RawMessage, error)
+	// notify sends a notification
+	notify(method string, params interface{}) error
+	// done is closed when the connection is lost
+	done() <-chan struct{}
+	close() error
+}
+
+// notificationHandler receives notifications sent by an upstream server
+type notificationHandler func(method string, params json.RawMessage)
+
+// Tool is a tool provided by an upstream server
+type Tool struct {
+	Name     string                 // Name exposed to clients (with prefix)
+	Upstream string                 // Owning upstream name
+	Original string                 // Name on the upstream server
+	Def      map[string]interface{} // Tool definition as returned by tools/list, renamed
+}
+
+// Manager owns the connections to all configured upstreams
+type Manager struct {
+	mu             sync.RWMutex
+	upstreams      map[string]*upstream
+	onToolsChanged func()
+}
+
+// NewManager creates an empty upstream manager
+func NewManager() *Manager {
+	return &Manager{upstreams: make(map[string]*upstream)}
+}
+
+// OnToolsChanged sets a callback invoked when an upstream's tool list changes
+func (m *Manager) OnToolsChanged(cb func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onToolsChanged = cb
+}
+
+// Update starts new upstreams, stops removed ones and restarts changed ones
+func (m *Manager) Update(cfgs []config.Upstream) {
+	m.mu.Lock()
+
+	wanted := make(map[string]config.Upstream, len(cfgs))
+	for _, cfg := range cfgs {
+		wanted[cfg.Name] = cfg
+	}
+
+	// Tools of stopped upstreams disappear right away
+	removed := false
+	for name, u := range m.upstreams {
+		if cfg, ok := wanted[name]; !ok || !reflect.DeepEqual(cfg, u.cfg) {
+			if len(u.toolList()) > 0 {
+				removed = true
+			}
+			u.stop()
+			delete(m.upstreams, name)
+		}
+	}
+
+	for name, cfg := range wanted {
+		if _, ok := m.upstreams[name]; ok {
+			continue
+		}
+		u := newUpstream(cfg, m.toolsChanged)
+		m.upstreams[name] = u
+		go u.run()
+	}
+	m.mu.Unlock()
+
+	if removed {
+		m.toolsChanged()
+	}
+}
+
+// Close stops all upstreams
+func (m *Manager) Close() {
+	m.Update(nil)
+}
+
+func (m *Manager) toolsChanged() {
+	m.mu.RLock()
+	cb := m.onToolsChanged
+	m.mu.RUnlock()
+	if cb != nil {
+		cb()
+	}
+}
+
+// Tools returns the tools of all connected upstreams, sorted by upstream name
+func (m *Manager) Tools() []Tool {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.upstreams))
+	for name := range m.upstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ups := make([]*upstream, len(names))
+	for i, name := range names {
+		ups[i] = m.upstreams[name]
+	}
+	m.mu.RUnlock()
+
+	var tools []Tool
+	for _, u := range ups {
+		tools = append(tools, u.toolList()...)
+	}
+	return tools
+}
+
+// GetTool returns the upstream tool exposed under name, or nil
+func (m *Manager) GetTool(name string) *Tool {
+	for _, tool := range m.Tools() {
+		if tool.Name == name {
+			t := tool
+			return &t
+		}
+	}
+	return nil
+}
+
+// CallTool forwards a tools/call to the upstream that owns the tool and
+// returns the upstream's raw result
+func (m *Manager) CallTool(ctx context.Context, tool *Tool, args map[string]interface{}) (json.RawMessage, error) {
+	m.mu.RLock()
+	u, ok := m.upstreams[tool.Upstream]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("upstream '%s' is not configured", tool.Upstream)
+	}
+	return u.callTool(ctx, tool.Original, args)
+}
+
+// upstream is a single supervised upstream server
+type upstream struct {
+	cfg            config.Upstream
+	timeout        time.Duration
+	onToolsChanged func()
+
+	mu     sync.RWMutex
+	conn   conn
+	tools  []Tool
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+func newUpstream(cfg config.Upstream, onToolsChanged func()) *upstream {
+	timeout := 30 * time.Second
+	if cfg.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.Timeout); err == nil {
+			timeout = d
+		}
+	}
+	return &upstream{
+		cfg:            cfg,
+		timeout:        timeout,
+		onToolsChanged: onToolsChanged,
+		stopCh:         make(chan struct{}),
+	}
+}
+
+func (u *upstream) stop() {
+	u.once.Do(func() {
+		close(u.stopCh)
+		u.mu.Lock()
+		if u.conn != nil {
+			u.conn.close()
+		}
+		u.mu.Unlock()
+	})
+}
+
+// run connects to the upstream and reconnects with backoff whenever the
+// connection is lost (e.g. a crashed stdio server), until stopped
+func (u *upstream) run() {
+	backoff := time.Second
+	for {
+		connectedAt := time.Now()
+		err := u.connect()
+		if err == nil {
+			slog.Info("Upstream connected", "upstream", u.cfg.Name, "tools", len(u.toolList()))
+
+			u.mu.RLock()
+			c := u.conn
+			u.mu.RUnlock()
+			select {
+			case <-c.done():
+			case <-u.stopCh:
+				return
+			}
+
+			u.mu.Lock()
+			u.conn = nil
+			u.tools = nil
+			u.mu.Unlock()
+			u.onToolsChanged()
+			err = fmt.Errorf("connection lost")
+		}
+
+		select {
+		case <-u.stopCh:
+			return
+		default:
+		}
+
+		// Reset the backoff after a connection that stayed up for a while
+		if time.Since(connectedAt) > time.Minute {
+			backoff = time.Second
+		}
+		slog.Warn("Upstream unavailable", "upstream", u.cfg.Name, "error", err, "retry_in", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-u.stopCh:
+			return
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (u *upstream) connect() error {
+	var c conn
+	var err error
+	if u.cfg.Command != "" {
+		c, err = dialStdio(u.cfg, u.handleNotification)
+	} else {
+		c, err = dialHTTP(u.cfg, u.handleNotification)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+	defer cancel()
+
+	_, err = c.call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo": map[string]interface{}{
+			"name":    "gantz",
+			"version": "1.0.0",
+		},
+	})
+	if err != nil {
+		c.close()
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if err := c.notify("notifications/initialized", nil); err != nil {
+		c.close()
+		return fmt.Errorf("initialize: %w", err)
+	}
+
+	tools, err := listTools(ctx, c, u.cfg)
+	if err != nil {
+		c.close()
+		return err
+	}
+
+	u.mu.Lock()
+	select {
+	case <-u.stopCh:
+		u.mu.Unlock()
+		c.close()
+		return fmt.Errorf("stopped")
+	default:
+	}
+	u.conn = c
+	u.tools = tools
+	u.mu.Unlock()
+	u.onToolsChanged()
+	return nil
+}
+
+func (u *upstream) handleNotification(method string, params json.RawMessage) {
+	if method != "notifications/tools/list_changed" {
+		return
+	}
+	go func() {
+		u.mu.RLock()
+		c := u.conn
+		u.mu.RUnlock()
+		if c == nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+		defer cancel()
+		tools, err := listTools(ctx, c, u.cfg)
+		if err != nil {
+			return
+		}
+		u.mu.Lock()
+		u.tools = tools
+		u.mu.Unlock()
+		u.onToolsChanged()
+	}()
+}
+
+func (u *upstream) toolList() []Tool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return append([]Tool(nil), u.tools...)
+}
+
+func (u *upstream) callTool(ctx context.Context, name string, args map[string]interface{}) (json.RawMessage, error) {
+	u.mu.RLock()
+	c := u.conn
+	u.mu.RUnlock()
+	if c == nil {
+		return nil, fmt.Errorf("upstream '%s' is not connected", u.cfg.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return c.call(ctx, "tools/call", map[string]interface{}{
+		"name":      name,
+		"arguments": args,
+	})
+}
+
+// listTools fetches all pages of an upstream's tools/list
+func listTools(ctx context.Context, c conn, cfg config.Upstream) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		raw, err := c.call(ctx, "tools/list", params)
+		if err != nil {
+			return nil, fmt.Errorf("tools/list: %w", err)
+		}
+
+		var result struct {
+			Tools      []map[string]interface{} `json:"tools"`
+			NextCursor string                   `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("tools/list: %w", err)
+		}
+
+		for _, def := range result.Tools {
+			original, _ := def["name"].(string)
+			if original == "" {
+				continue
+			}
+			name := cfg.Prefix + original
+			def["name"] = name
+			tools = append(tools, Tool{
+				Name:     name,
+				Upstream: cfg.Name,
+				Original: original,
+				Def:      def,
+			})
+		}
+
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// rpcMessage is any JSON-RPC message read from an upstream
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// isResponse reports whether a message answers one of our requests
+func (m *rpcMessage) isResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+// pendingCalls correlates responses with outstanding requests
+type pendingCalls struct {
+	mu     sync.Mutex
+	nextID int64
+	calls  map[string]chan *rpcMessage
+}
+
+func newPendingCalls() *pendingCalls {
+	return &pendingCalls{calls: make(map[string]chan *rpcMessage)}
+}
+
+func (p *pendingCalls) add() (int64, chan *rpcMessage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextID++
+	ch := make(chan *rpcMessage, 1)
+	p.calls[fmt.Sprint(p.nextID)] = ch
+	return p.nextID, ch
+}
+
+func (p *pendingCalls) remove(id int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.calls, fmt.Sprint(id))
+}
+
+func (p *pendingCalls) deliver(msg *rpcMessage) {
+	key := strings.Trim(string(msg.ID), `"`)
+	p.mu.Lock()
+	ch, ok := p.calls[key]
+	delete(p.calls, key)
+	p.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+// wait blocks until the response arrives, the context expires or the connection is lost
+func (p *pendingCalls) wait(ctx context.Context, id int64, ch chan *rpcMessage, done <-chan struct{}) (json.RawMessage, error) {
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return nil, msg.Error
+		}
+		return msg.Result, nil
+	case <-ctx.Done():
+		p.remove(id)
+		return nil, ctx.Err()
+	case <-done:
+		p.remove(id)
+		return nil, fmt.Errorf("connection lost")
+	}
+}
+
+func newRequest(id int64, method string, params interface{}) map[string]interface{} {
+	req := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+	}
+	if params != nil {
+		req["params"] = params
+	}
+	return req
+}
+
+// replyToRequest answers a request sent by the upstream server. Only ping is supported.
+func replyToRequest(msg *rpcMessage) map[string]interface{} {
+	reply := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      msg.ID,
+	}
+	if msg.Method == "ping" {
+		reply["result"] = map[string]interface{}{}
+	} else {
+		reply["error"] = rpcError{Code: -32601, Message: "Method not found: " + msg.Method}
+	}
+	return reply
+}
+
+func newNotification(method string, params interface{}) map[string]interface{} {
+	msg := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+	}
+	if params != nil {
+		msg["params"] = params
+	}
+	return msg
+}